@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Ways a page redirects in the browser instead of with an http redirect
+const (
+	viaMetaRefresh = "meta-refresh"
+	viaScript      = "script"
+)
+
+// clientRedirect is a redirect of a page done by the browser, with a meta
+// refresh or by a script assigning the location
+type clientRedirect struct {
+	Url        string `json:"url"`
+	Page       string `json:"page"`
+	Via        string `json:"via"`
+	ThirdParty bool   `json:"thirdParty"`
+}
+
+// locationRegexps match url literals assigned to location or location.href,
+// or passed to location.replace() and location.assign() in code
+var locationRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`\blocation(?:\.href)?\s*=\s*["'` + "`" + `]([^"'` + "`" + `\s]+)`),
+	regexp.MustCompile(`\blocation\.(?:replace|assign)\(\s*["'` + "`" + `]([^"'` + "`" + `\s]+)`),
+}
+
+// metaRefreshUrl returns the url of the content of a refresh meta tag, like
+// "0; url=https://example.com/", or "" if it only reloads the page
+func metaRefreshUrl(content string) string {
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return ""
+	}
+	target := strings.TrimSpace(content[i+1:])
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	return strings.Trim(target, `"'`)
+}
+
+// findScriptLocations returns the urls code sends the browser to, resolved
+// against base
+func findScriptLocations(code string, base *url.URL) []string {
+	var locations []string
+	for _, re := range locationRegexps {
+		for _, m := range re.FindAllStringSubmatch(code, -1) {
+			locations = append(locations, resolveUrl(base, m[1]))
+		}
+	}
+	return locations
+}
+
+// addClientRedirect records a redirect of a page in the browser once, urls
+// which are not http or https like javascript: are ignored.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addClientRedirect(target, via, domain string, page *url.URL) {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	finding := clientRedirect{Url: target, Page: page.String(), Via: via, ThirdParty: !isSameDomain(target, domain)}
+	for _, known := range scanResult.clientRedirects {
+		if known == finding {
+			return
+		}
+	}
+	scanResult.clientRedirects = append(scanResult.clientRedirects, finding)
+	scanResult.emit("clientRedirects", finding.Url, finding.Page, finding)
+	if finding.ThirdParty {
+		scanResult.logger(finding.Page).Info("3RD PARTY client redirect", "via", via, "page", finding.Page, "url", target)
+	} else {
+		slog.Debug("CLIENT REDIRECT", "via", via, "page", finding.Page, "url", target)
+	}
+}
+
+// thirdPartyClientRedirects returns the targets of redirects in the browser
+// to 3rd party hosts
+func (scanResult *ScanResult) thirdPartyClientRedirects() []string {
+	var urls []string
+	for _, redirect := range scanResult.clientRedirects {
+		if redirect.ThirdParty {
+			urls = append(urls, redirect.Url)
+		}
+	}
+	return urls
+}
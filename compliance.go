@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/exp/slices"
+)
+
+// maxFlaggedHosts is the number of hosts not on the allowlist reported by
+// their frequency
+const maxFlaggedHosts = 10
+
+// hostPages is a 3rd party host with the number of pages it was found on
+type hostPages struct {
+	Host  string `json:"host"`
+	Pages int    `json:"pages"`
+}
+
+// compliance is how many of the 3rd party hosts of a website are on the
+// allowlist, with the hosts not on it found on the most pages
+type compliance struct {
+	ApprovedHosts int         `json:"approvedHosts"`
+	TotalHosts    int         `json:"totalHosts"`
+	Percent       float64     `json:"percent"`
+	TopFlagged    []hostPages `json:"topFlagged"`
+}
+
+// compliance counts the approved 3rd party hosts and ranks the others by
+// the number of pages loading resources of them. Like for the statistics
+// inline references are left out, the website doesn't contact them.
+func (scanResult *ScanResult) compliance() compliance {
+	result := compliance{Percent: 100, TopFlagged: []hostPages{}}
+	var hosts []string
+	pages := map[string][]string{}
+	for _, list := range scanResult.resourceLists() {
+		if list.resourceType == "inline-reference" {
+			continue
+		}
+		for _, u := range list.urls {
+			host := resourceHost(u)
+			if !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+			for _, page := range scanResult.foundOn[u] {
+				if !slices.Contains(pages[host], page) {
+					pages[host] = append(pages[host], page)
+				}
+			}
+		}
+	}
+	for _, host := range hosts {
+		if isApproved(host) {
+			result.ApprovedHosts += 1
+		} else {
+			result.TopFlagged = append(result.TopFlagged, hostPages{Host: host, Pages: len(pages[host])})
+		}
+	}
+	result.TotalHosts = len(hosts)
+	if result.TotalHosts > 0 {
+		result.Percent = float64(result.ApprovedHosts) * 100 / float64(result.TotalHosts)
+	}
+	slices.SortFunc(result.TopFlagged, func(a, b hostPages) bool {
+		if a.Pages != b.Pages {
+			return a.Pages > b.Pages
+		}
+		return a.Host < b.Host
+	})
+	if len(result.TopFlagged) > maxFlaggedHosts {
+		result.TopFlagged = result.TopFlagged[:maxFlaggedHosts]
+	}
+	return result
+}
+
+// printCompliance writes the share of approved 3rd party hosts and the
+// hosts not on the allowlist found on the most pages
+func printCompliance(w io.Writer, c compliance, color func(string) string) {
+	fmt.Fprintf(w, "Allowlist compliance: %d of %d 3rd Party hosts approved (%.1f%%)\n", c.ApprovedHosts, c.TotalHosts, c.Percent)
+	if len(c.TopFlagged) > 0 {
+		fmt.Fprintln(w, color(colorRed)+"Hosts not on the allowlist by the number of pages loading them:"+color(colorReset))
+		for _, host := range c.TopFlagged {
+			fmt.Fprintf(w, "  %s (%d)\n", host.Host, host.Pages)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// decompressTransport asks for compressed responses and decodes gzip,
+// deflate and brotli bodies, so stylesheets can be scanned as plain text
+type decompressTransport struct {
+	base http.RoundTripper
+}
+
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var body io.Reader
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "br":
+		body = brotli.NewReader(resp.Body)
+	case "gzip", "x-gzip":
+		if body, err = gzip.NewReader(resp.Body); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+	case "deflate":
+		body = newDeflateReader(resp.Body)
+	default:
+		return resp, nil
+	}
+
+	resp.Body = &decodedBody{Reader: body, raw: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// newDeflateReader handles both zlib wrapped and raw deflate streams,
+// as servers disagree on what "deflate" means
+func newDeflateReader(r io.Reader) io.Reader {
+	buffered := bufio.NewReader(r)
+	header, err := buffered.Peek(2)
+	// a zlib header has a compression method of 8 and is a multiple of 31
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		if z, err := zlib.NewReader(buffered); err == nil {
+			return z
+		}
+	}
+	return flate.NewReader(buffered)
+}
+
+// decodedBody closes the original body once the decoded one is closed
+type decodedBody struct {
+	io.Reader
+	raw io.ReadCloser
+}
+
+func (b *decodedBody) Close() error {
+	return b.raw.Close()
+}
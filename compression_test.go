@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// TestBrotliStylesheet checks the @imports of a brotli encoded stylesheet
+// are found
+func TestBrotliStylesheet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="stylesheet" href="/style.css">`)
+		case "/style.css":
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "br") {
+				t.Errorf("stylesheet requested with Accept-Encoding %q, want br", r.Header.Get("Accept-Encoding"))
+			}
+			w.Header().Set("Content-Type", "text/css")
+			w.Header().Set("Content-Encoding", "br")
+			bw := brotli.NewWriter(w)
+			fmt.Fprint(bw, `@import url("https://fonts.googleapis.com/css?family=Brotli");`)
+			bw.Close()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	scanResult := scan(t, server.URL+"/")
+	want := []string{"https://fonts.googleapis.com/css?family=Brotli"}
+	if fmt.Sprint(scanResult.googleFontsCss) != fmt.Sprint(want) {
+		t.Errorf("Google Fonts imports = %v, want %v", scanResult.googleFontsCss, want)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fileConfig holds the options which can be set in a -config file. Each
+// field is applied to the flag named by its flag tag, or by its yaml key if
+// there is none, unless the flag was given on the command line. Fields
+// tagged with flag:"-" have no flag and are applied on their own. All flags
+// have a key except -config itself and -version, which only prints the
+// version.
+type fileConfig struct {
+	UrlFile           *string        `yaml:"url-file" flag:"f"`
+	BaseUrl           *string        `yaml:"base-url"`
+	Depth             *int           `yaml:"depth" flag:"d"`
+	DepthPerHost      *int           `yaml:"depth-per-host"`
+	NoCrawl           *bool          `yaml:"no-crawl"`
+	MaxPages          *int           `yaml:"max" flag:"max"`
+	MaxBody           *int           `yaml:"max-body"`
+	Verbose           *bool          `yaml:"verbose" flag:"v"`
+	VeryVerbose       *bool          `yaml:"very-verbose" flag:"vv"`
+	LogFormat         *string        `yaml:"log-format"`
+	UserAgent         *string        `yaml:"user-agent" flag:"ua"`
+	UserAgentList     *string        `yaml:"user-agent-list" flag:"ua-list"`
+	RobotsUserAgent   *string        `yaml:"robots-user-agent" flag:"robots-ua"`
+	Headers           []string       `yaml:"headers" flag:"header"`
+	Cookies           []string       `yaml:"cookies" flag:"cookie"`
+	CookieFile        *string        `yaml:"cookie-file"`
+	BasicAuth         *string        `yaml:"basic-auth"`
+	Proxy             *string        `yaml:"proxy"`
+	Insecure          *bool          `yaml:"insecure"`
+	Delay             *time.Duration `yaml:"delay"`
+	RandomDelay       *time.Duration `yaml:"random-delay"`
+	Parallelism       *int           `yaml:"parallelism"`
+	Concurrency       *int           `yaml:"concurrency"`
+	Retries           *int           `yaml:"retries"`
+	Timeout           *time.Duration `yaml:"timeout"`
+	RequestTimeout    *time.Duration `yaml:"request-timeout"`
+	Sitemap           *bool          `yaml:"sitemap"`
+	CheckExternal     *bool          `yaml:"check-external"`
+	ScanScripts       *bool          `yaml:"scan-scripts"`
+	ImportDepth       *int           `yaml:"import-depth"`
+	IgnoreQuery       *bool          `yaml:"ignore-query"`
+	IgnoreRobots      *bool          `yaml:"ignore-robots"`
+	IncludeSubdomains *bool          `yaml:"include-subdomains"`
+	SameSite          *bool          `yaml:"same-site"`
+	CrawlDomains      []string       `yaml:"crawl-domains" flag:"crawl-domain"`
+	Paginate          []string       `yaml:"paginate"`
+	Include           *string        `yaml:"include"`
+	Exclude           *string        `yaml:"exclude"`
+	Only              *string        `yaml:"only"`
+	Json              *bool          `yaml:"json"`
+	Summary           *bool          `yaml:"summary"`
+	Table             *bool          `yaml:"table"`
+	DiscoveryOrder    *bool          `yaml:"discovery-order"`
+	Stream            *bool          `yaml:"stream"`
+	ListUrls          *bool          `yaml:"list-urls"`
+	Output            *string        `yaml:"output" flag:"o"`
+	Html              *string        `yaml:"html"`
+	Csv               *string        `yaml:"csv"`
+	Diff              *string        `yaml:"diff"`
+	Sarif             *string        `yaml:"sarif"`
+	SaveDir           *string        `yaml:"save-dir"`
+	CacheDir          *string        `yaml:"cache-dir"`
+	NoCache           *bool          `yaml:"no-cache"`
+	Timings           *bool          `yaml:"timings"`
+	Metrics           *string        `yaml:"metrics"`
+	OtelEndpoint      *string        `yaml:"otel-endpoint"`
+	NoColor           *bool          `yaml:"no-color"`
+	FailOn            *string        `yaml:"fail-on"`
+	AllowDomains      []string       `yaml:"allow-domains" flag:"allow-domain"`
+	Allowlist         *string        `yaml:"allowlist"`
+	Rules             *string        `yaml:"rules"`
+	ScoreWeights      map[string]int `yaml:"score-weights" flag:"-"`
+	GoogleAnalytics   []string       `yaml:"google-analytics-hosts" flag:"-"`
+	GoogleTagManager  []string       `yaml:"google-tag-manager-hosts" flag:"-"`
+	GoogleFonts       []string       `yaml:"google-fonts-hosts" flag:"-"`
+}
+
+// readConfigFile decodes a yaml config file, rejecting unknown keys
+func readConfigFile(path string) (*fileConfig, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var config fileConfig
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// apply sets the flags from the config file which were not given on the
+// command line
+func (config *fileConfig) apply() error {
+	// flags are compared by value, so an alias given on the command line
+	// takes precedence as well
+	given := map[flag.Value]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Value] = true
+	})
+
+	value := reflect.ValueOf(config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name := field.Tag.Get("flag")
+		if name == "" {
+			name = field.Tag.Get("yaml")
+		}
+		if name == "-" {
+			continue
+		}
+		f := flag.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("config: no flag %q", name)
+		}
+		if given[f.Value] || value.Field(i).IsNil() {
+			continue
+		}
+
+		var values []string
+		if list, ok := value.Field(i).Interface().([]string); ok {
+			values = list
+		} else {
+			values = []string{fmt.Sprint(value.Field(i).Elem().Interface())}
+		}
+		for _, v := range values {
+			if err := flag.Set(name, v); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %w", v, field.Tag.Get("yaml"), err)
+			}
+		}
+	}
+	if len(config.GoogleAnalytics) > 0 {
+		googleAnalyticsHosts = config.GoogleAnalytics
+	}
+	if len(config.GoogleTagManager) > 0 {
+		googleTagManagerHosts = config.GoogleTagManager
+	}
+	if len(config.GoogleFonts) > 0 {
+		googleFontsHosts = config.GoogleFonts
+	}
+	return setScoreWeights(config.ScoreWeights)
+}
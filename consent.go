@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+)
+
+// consentPlatform is a consent management platform showing the cookie
+// banner, recognized by the hosts of its scripts or by the globals of its
+// code in inline scripts
+type consentPlatform struct {
+	name    string
+	hosts   []string
+	globals []string
+}
+
+// consentPlatforms are the known consent management platforms, matched in
+// order. Borlabs Cookie and Klaro are self-hosted and only have globals.
+var consentPlatforms = []consentPlatform{
+	{"OneTrust", []string{"cookielaw.org", "onetrust.com", "cookiepro.com"}, []string{"OneTrust", "OptanonWrapper"}},
+	{"Cookiebot", []string{"cookiebot.com", "cookiebot.eu"}, []string{"Cookiebot", "CookieConsent"}},
+	{"Usercentrics", []string{"usercentrics.eu"}, []string{"UC_UI", "usercentrics"}},
+	{"Didomi", []string{"privacy-center.org"}, []string{"didomiConfig", "Didomi"}},
+	{"Quantcast Choice", []string{"quantcast.mgr.consensu.org", "cmp.quantcast.com"}, []string{"__qcCmp"}},
+	{"TrustArc", []string{"trustarc.com"}, nil},
+	{"consentmanager", []string{"consentmanager.net"}, nil},
+	{"CookieYes", []string{"cdn-cookieyes.com"}, []string{"cookieyes"}},
+	{"iubenda", []string{"iubenda.com"}, []string{"_iub.csConfiguration"}},
+	{"Borlabs Cookie", nil, []string{"BorlabsCookie"}},
+	{"Klaro", nil, []string{"klaroConfig"}},
+}
+
+// consentPlatformOfUrl returns the name of the consent management platform
+// serving a script, or ""
+func consentPlatformOfUrl(u string) string {
+	host := resourceHost(u)
+	for _, platform := range consentPlatforms {
+		for _, h := range platform.hosts {
+			if host == h || strings.HasSuffix(host, "."+h) {
+				return platform.name
+			}
+		}
+	}
+	return ""
+}
+
+// consentPlatformOfCode returns the name of the consent management platform
+// whose globals are used by inline code, or ""
+func consentPlatformOfCode(code string) string {
+	for _, platform := range consentPlatforms {
+		for _, global := range platform.globals {
+			if strings.Contains(code, global) {
+				return platform.name
+			}
+		}
+	}
+	return ""
+}
+
+// setConsentPlatform records the consent management platform of the
+// website, the first one detected wins. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) setConsentPlatform(name, evidence, page string) {
+	if name == "" || scanResult.consentPlatform != "" {
+		return
+	}
+	scanResult.consentPlatform = name
+	scanResult.logger(page).Info("CONSENT PLATFORM", "platform", name, "evidence", evidence, "page", page)
+}
+
+// missingConsent reports whether trackers were found on a website without a
+// consent management platform, which usually means they run without consent
+func (scanResult *ScanResult) missingConsent() bool {
+	if scanResult.consentPlatform != "" {
+		return false
+	}
+	return scanResult.worstFinding() == severityAnalytics || len(scanResult.trackers) > 0 ||
+		len(scanResult.trackingPixels) > 0 || len(scanResult.matomo) > 0
+}
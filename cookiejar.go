@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseCookies turns "name=value" strings into cookies
+func parseCookies(values []string) ([]*http.Cookie, error) {
+	var cookies []*http.Cookie
+	for _, value := range values {
+		name, content, found := strings.Cut(value, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid cookie %q, expected \"name=value\"", value)
+		}
+		cookies = append(cookies, &http.Cookie{Name: name, Value: strings.TrimSpace(content)})
+	}
+	return cookies, nil
+}
+
+// fileCookie is a cookie of a cookie file with the host it belongs to
+type fileCookie struct {
+	host       string
+	subdomains bool
+	cookie     *http.Cookie
+}
+
+// readCookieFile reads the cookies of a cookie jar in the Netscape format as
+// written by curl and browser extensions, with the fields domain, include
+// subdomains, path, secure, expiry, name and value separated by tabs
+func readCookieFile(path string) ([]fileCookie, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var cookies []fileCookie
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		// curl marks HttpOnly cookies with a prefix looking like a comment
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: expected 7 tab separated fields, got %d", line, len(fields))
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", line, fields[4])
+		}
+		cookie := &http.Cookie{
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Value:    fields[6],
+		}
+		// an expiry of 0 marks a session cookie
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, fileCookie{
+			host:       strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			subdomains: strings.EqualFold(fields[1], "TRUE"),
+			cookie:     cookie,
+		})
+	}
+	return cookies, scanner.Err()
+}
+
+// cookiesFor returns the cookies of a cookie file which belong to the host
+func cookiesFor(cookies []fileCookie, host string) []*http.Cookie {
+	host = strings.ToLower(host)
+	var matching []*http.Cookie
+	for _, c := range cookies {
+		if c.host == host || (c.subdomains && strings.HasSuffix(host, "."+c.host)) {
+			matching = append(matching, c.cookie)
+		}
+	}
+	return matching
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// cspOrigin is a 3rd party source allowed by the Content-Security-Policy of
+// the website, with the directives allowing it
+type cspOrigin struct {
+	Origin     string   `json:"origin"`
+	Directives []string `json:"directives"`
+	ReportOnly bool     `json:"reportOnly"`
+	Loaded     bool     `json:"loaded"`
+}
+
+// addCspOrigins records the 3rd party sources of the Content-Security-Policy
+// headers of a response. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addCspOrigins(header http.Header, domain string) {
+	for _, name := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		for _, policy := range header.Values(name) {
+			for _, directive := range strings.Split(policy, ";") {
+				// all fetch directives end with -src, or with -src-elem and
+				// -src-attr
+				fields := strings.Fields(strings.ToLower(directive))
+				if len(fields) < 2 || !strings.Contains(fields[0], "-src") {
+					continue
+				}
+				for _, source := range fields[1:] {
+					if !isCspHostSource(source) || isSameDomain("//"+strings.TrimPrefix(cspHost(source), "*."), domain) {
+						continue
+					}
+					scanResult.addCspOrigin(source, fields[0], name != "Content-Security-Policy")
+				}
+			}
+		}
+	}
+}
+
+// addCspOrigin records a source allowed by a directive, which is report only
+// unless any policy enforces it
+func (scanResult *ScanResult) addCspOrigin(source, directive string, reportOnly bool) {
+	for i := range scanResult.cspOrigins {
+		origin := &scanResult.cspOrigins[i]
+		if origin.Origin == source {
+			if !slices.Contains(origin.Directives, directive) {
+				origin.Directives = append(origin.Directives, directive)
+			}
+			origin.ReportOnly = origin.ReportOnly && reportOnly
+			return
+		}
+	}
+	scanResult.cspOrigins = append(scanResult.cspOrigins, cspOrigin{
+		Origin:     source,
+		Directives: []string{directive},
+		ReportOnly: reportOnly,
+	})
+}
+
+// isCspHostSource reports whether a CSP source expression allows other
+// hosts, unlike keywords like 'self', nonces, hashes and data: or blob:
+func isCspHostSource(source string) bool {
+	if strings.HasPrefix(source, "'") {
+		return false
+	}
+	switch strings.ToLower(source) {
+	case "data:", "blob:", "mediastream:", "filesystem:":
+		return false
+	}
+	return true
+}
+
+// cspHost returns the host pattern of a CSP source expression like
+// https://*.example.com:443/path, which is "*" for any host
+func cspHost(source string) string {
+	if source == "*" || strings.HasSuffix(source, ":") {
+		return "*"
+	}
+	if _, rest, found := strings.Cut(source, "://"); found {
+		source = rest
+	}
+	source, _, _ = strings.Cut(source, "/")
+	if host, port, found := strings.Cut(source, ":"); found && port != "" {
+		source = host
+	}
+	return strings.ToLower(source)
+}
+
+// matchesCspSource reports whether a resource url is allowed by the host
+// pattern of a CSP source expression
+func matchesCspSource(resource, source string) bool {
+	pattern := cspHost(source)
+	if pattern == "*" {
+		return true
+	}
+	host := resource
+	if u, err := url.Parse(resource); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(host)
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return host == pattern
+}
+
+// checkCspOrigins marks the CSP origins of which resources were found
+func (scanResult *ScanResult) checkCspOrigins() []cspOrigin {
+	origins := slices.Clone(scanResult.cspOrigins)
+	for i := range origins {
+		for _, list := range scanResult.resourceLists() {
+			for _, resource := range list.urls {
+				if matchesCspSource(resource, origins[i].Origin) {
+					origins[i].Loaded = true
+				}
+			}
+		}
+	}
+	return origins
+}
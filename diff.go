@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/exp/slices"
+)
+
+// exitNewTrackers is the exit status of -diff if trackers appeared which
+// were not found by the previous scan
+const exitNewTrackers = 6
+
+// scanDiff is what changed between two scans of a website
+type scanDiff struct {
+	url             string
+	addedHosts      []string
+	removedHosts    []string
+	newTrackers     []string
+	removedTrackers []string
+	addedFonts      []string
+	removedFonts    []string
+}
+
+// readJsonResults reads the websites of a file written by -json, which holds
+// one JSON object per website
+func readJsonResults(path string) ([]jsonResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []jsonResult
+	decoder := json.NewDecoder(file)
+	for {
+		var result jsonResult
+		if err := decoder.Decode(&result); errors.Is(err, io.EOF) {
+			return results, nil
+		} else if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+}
+
+// findJsonResult returns the result of the website among results, or an empty
+// result if the website wasn't scanned before
+func findJsonResult(results []jsonResult, u string) jsonResult {
+	for _, result := range results {
+		if result.Url == u {
+			return result
+		}
+	}
+	return jsonResult{Url: u}
+}
+
+// resultHosts returns the distinct 3rd party hosts of a result, the same as
+// counted by the statistics and the compliance: the hosts of the resources
+// the website loads. Hosts only mentioned in inline code and the self-hosted
+// Matomo trackers are left out.
+func resultHosts(result jsonResult) []string {
+	var hosts []string
+	for resource := range result.FoundOn {
+		if slices.Contains(result.Matomo, resource) || slices.Contains(result.InlineReferences, resource) {
+			continue
+		}
+		if host := resourceHost(resource); !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	slices.Sort(hosts)
+	return hosts
+}
+
+// resultTrackers names the trackers of a result, Google Analytics and Tag
+// Manager with their ids and tracking pixels by host
+func resultTrackers(result jsonResult) []string {
+	var trackers []string
+	for _, tracker := range result.Trackers {
+		name := tracker.Tracker
+		if tracker.Id != "" {
+			name += " " + tracker.Id
+		}
+		trackers = append(trackers, name)
+	}
+	for _, pixel := range result.TrackingPixels {
+		if name := "tracking pixel " + resourceHost(pixel); !slices.Contains(trackers, name) {
+			trackers = append(trackers, name)
+		}
+	}
+	return trackers
+}
+
+// resultFonts returns the urls of the fonts and font stylesheets of a result
+func resultFonts(result jsonResult) []string {
+	fonts := append(append(slices.Clone(result.RemoteFonts), result.GoogleFontsCss...), result.GoogleFontsStyle...)
+	for resource := range result.FoundOn {
+		if isGoogleFontsUrl(resource) {
+			fonts = append(fonts, resource)
+		}
+	}
+	slices.Sort(fonts)
+	return slices.Compact(fonts)
+}
+
+// missingFrom returns the values of a which are not in b
+func missingFrom(a, b []string) []string {
+	var missing []string
+	for _, v := range a {
+		if !slices.Contains(b, v) {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// diffResults compares the current result of a website with a previous one
+func diffResults(previous, current jsonResult) scanDiff {
+	previousHosts, currentHosts := resultHosts(previous), resultHosts(current)
+	previousTrackers, currentTrackers := resultTrackers(previous), resultTrackers(current)
+	previousFonts, currentFonts := resultFonts(previous), resultFonts(current)
+	return scanDiff{
+		url:             current.Url,
+		addedHosts:      missingFrom(currentHosts, previousHosts),
+		removedHosts:    missingFrom(previousHosts, currentHosts),
+		newTrackers:     missingFrom(currentTrackers, previousTrackers),
+		removedTrackers: missingFrom(previousTrackers, currentTrackers),
+		addedFonts:      missingFrom(currentFonts, previousFonts),
+		removedFonts:    missingFrom(previousFonts, currentFonts),
+	}
+}
+
+// printDiff writes the changes of a website, new trackers in red
+func printDiff(w io.Writer, diff scanDiff, useColor bool) {
+	color := colorizer(useColor).color
+	section := func(title string, values []string, code string) {
+		if len(values) == 0 {
+			return
+		}
+		fmt.Fprintln(w, color(code)+title+color(colorReset))
+		for _, v := range values {
+			fmt.Fprintln(w, "  "+v)
+		}
+	}
+	fmt.Fprintln(w, "Changes of", diff.url, "since the previous scan:")
+	section("New trackers:", diff.newTrackers, colorRed)
+	section("Removed trackers:", diff.removedTrackers, colorReset)
+	section("Added 3rd party hosts:", diff.addedHosts, colorYellow)
+	section("Removed 3rd party hosts:", diff.removedHosts, colorReset)
+	section("Added fonts:", diff.addedFonts, colorYellow)
+	section("Removed fonts:", diff.removedFonts, colorReset)
+	if len(diff.newTrackers)+len(diff.removedTrackers)+len(diff.addedHosts)+len(diff.removedHosts)+len(diff.addedFonts)+len(diff.removedFonts) == 0 {
+		fmt.Fprintln(w, "  no changes")
+	}
+}
+
+// diffFiles compares the results in the files written by -json with the
+// previous ones and reports whether new trackers appeared
+func diffFiles(w io.Writer, previous []jsonResult, paths []string, useColor bool) (bool, error) {
+	newTrackers := false
+	for _, path := range paths {
+		results, err := readJsonResults(path)
+		if err != nil {
+			return false, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		for _, current := range results {
+			diff := diffResults(findJsonResult(previous, current.Url), current)
+			printDiff(w, diff, useColor)
+			newTrackers = newTrackers || len(diff.newTrackers) > 0
+		}
+	}
+	return newTrackers, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestDiffHostsLikeStats checks the hosts compared by -diff are the ones
+// counted by the statistics and the compliance of the same scan
+func TestDiffHostsLikeStats(t *testing.T) {
+	server := serveFiles(t, map[string]string{
+		"/index.html": `<script src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+<script src="/matomo/matomo.js"></script>
+<script src="https://cdn.other.com/app.js"></script>
+<img src="https://img.other.com/photo.jpg">
+<script>loadPixel("connect.facebook.net");</script>`,
+	})
+	scanResult := scan(t, server.URL+"/")
+	if len(scanResult.inlineReferences) == 0 || len(scanResult.matomo) == 0 {
+		t.Fatalf("found inline references %v and Matomo %v, want both", scanResult.inlineReferences, scanResult.matomo)
+	}
+
+	hosts := resultHosts(newJsonResult(scanResult))
+	want := []string{"cdn.other.com", "img.other.com", "www.googletagmanager.com"}
+	if fmt.Sprint(hosts) != fmt.Sprint(want) {
+		t.Errorf("hosts = %v, want %v", hosts, want)
+	}
+	if stats := scanResult.Stats(); stats.ThirdPartyHosts != len(hosts) {
+		t.Errorf("statistics count %d hosts, diff compares %v", stats.ThirdPartyHosts, hosts)
+	}
+	if compliance := scanResult.compliance(); compliance.TotalHosts != len(hosts) {
+		t.Errorf("compliance counts %d hosts, diff compares %v", compliance.TotalHosts, hosts)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// scriptEndpointRegexps match absolute url literals passed to fetch(), new
+// WebSocket() and XMLHttpRequest.open() in code
+var scriptEndpointRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:fetch|new\s+WebSocket)\(\s*["'` + "`" + `]((?:https?:|wss?:)?//[^"'` + "`" + `\s]+)`),
+	regexp.MustCompile(`\.open\(\s*["'][A-Za-z]+["']\s*,\s*["'` + "`" + `]((?:https?:|wss?:)?//[^"'` + "`" + `\s]+)`),
+}
+
+// findScriptEndpoints returns the urls code calls with fetch, WebSocket or
+// XMLHttpRequest, resolved against the url of the code
+func findScriptEndpoints(code string, base *url.URL) []string {
+	var endpoints []string
+	for _, re := range scriptEndpointRegexps {
+		for _, m := range re.FindAllStringSubmatch(code, -1) {
+			endpoints = append(endpoints, resolveUrl(base, m[1]))
+		}
+	}
+	return endpoints
+}
+
+// isThirdPartyEndpoint reports whether an endpoint belongs to another host,
+// WebSocket urls are compared like their http counterparts
+func isThirdPartyEndpoint(endpoint, domain string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "ws":
+		u.Scheme = "http"
+	case "wss":
+		u.Scheme = "https"
+	}
+	return !isSameDomain(u.String(), domain)
+}
+
+// addScriptEndpoints records the 3rd party endpoints called by code found
+// at where. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addScriptEndpoints(code, where, domain string, base *url.URL, page string) {
+	for _, endpoint := range findScriptEndpoints(code, base) {
+		if !isThirdPartyEndpoint(endpoint, domain) {
+			continue
+		}
+		scanResult.add(&scanResult.scriptEndpoints, endpoint, page)
+		scanResult.logger(page).Info("3RD PARTY endpoint, heuristic, unknown if that code executed", "in", where, "page", page, "url", endpoint)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// externalCheckTimeout is the max duration of checking a single external link
+const externalCheckTimeout = 10 * time.Second
+
+// externalCheck is where an external link of the website ends up after
+// following its redirects
+type externalCheck struct {
+	Url      string `json:"url"`
+	FinalUrl string `json:"finalUrl"`
+	Status   int    `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// addExternalLink remembers the first link to each external host for
+// -check-external. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addExternalLink(link string) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	for _, known := range scanResult.externalLinks {
+		if k, err := url.Parse(known); err == nil && k.Host == u.Host {
+			return
+		}
+	}
+	scanResult.externalLinks = append(scanResult.externalLinks, link)
+}
+
+// checkExternalLinks resolves each external link with a HEAD request, or a
+// GET request without reading the body if HEAD is not allowed
+func checkExternalLinks(ctx context.Context, transport http.RoundTripper, links []string) []externalCheck {
+	client := &http.Client{Transport: transport, Timeout: externalCheckTimeout}
+	var checks []externalCheck
+	for _, link := range links {
+		if ctx.Err() != nil {
+			break
+		}
+		check := externalCheck{Url: link}
+		resp, err := requestExternal(ctx, client, http.MethodHead, link)
+		if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+			resp.Body.Close()
+			resp, err = requestExternal(ctx, client, http.MethodGet, link)
+		}
+		if err != nil {
+			check.Error = err.Error()
+		} else {
+			resp.Body.Close()
+			check.FinalUrl = resp.Request.URL.String()
+			check.Status = resp.StatusCode
+		}
+		slog.Info("CHECKED external link", "url", link, "finalUrl", check.FinalUrl, "status", check.Status, "error", check.Error)
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// requestExternal sends a request with the User-Agent of the crawl but none
+// of its headers or credentials, which are meant for the website only
+func requestExternal(ctx context.Context, client *http.Client, method, link string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", *userAgent)
+	return client.Do(req)
+}
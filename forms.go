@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// formEndpoint is a 3rd party url forms of the website send their input to,
+// with the pages the forms were found on
+type formEndpoint struct {
+	Url    string   `json:"url"`
+	Method string   `json:"method"`
+	Pages  []string `json:"pages"`
+}
+
+// addFormEndpoint records the 3rd party action of a form, forms without a
+// method send GET requests. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addFormEndpoint(action, method, page string) {
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if method == "" {
+		method = "GET"
+	}
+	i := slices.IndexFunc(scanResult.formEndpoints, func(f formEndpoint) bool {
+		return f.Url == action && f.Method == method
+	})
+	if i < 0 {
+		scanResult.formEndpoints = append(scanResult.formEndpoints, formEndpoint{Url: action, Method: method})
+		i = len(scanResult.formEndpoints) - 1
+		scanResult.logger(page).Info("3RD PARTY <form>", "page", page, "method", method, "url", action)
+	}
+	if !slices.Contains(scanResult.formEndpoints[i].Pages, page) {
+		scanResult.formEndpoints[i].Pages = append(scanResult.formEndpoints[i].Pages, page)
+		scanResult.emit("formEndpoints", action, page, formEndpoint{Url: action, Method: method, Pages: []string{page}})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// defaultLocalBaseUrl is used as address of local pages without -base-url
+const defaultLocalBaseUrl = "http://localhost/"
+
+// isLocalSource reports whether the argument is a local file or stdin
+// instead of a website
+func isLocalSource(source string) bool {
+	return source == "-" || strings.HasPrefix(source, "file://")
+}
+
+// readLocalPage reads the html of a file:// url or of stdin for "-"
+func readLocalPage(source string) ([]byte, error) {
+	if source == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(u.Path)
+}
+
+// localTransport serves a local page under its base url, so it passes
+// through the same handlers as a page of a website
+type localTransport struct {
+	pageUrl string
+	body    []byte
+}
+
+func (t *localTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	body := t.body
+	if req.URL.String() != t.pageUrl {
+		status = http.StatusNotFound
+		body = nil
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}, nil
+}
@@ -1,397 +1,3247 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
+	"golang.org/x/exp/maps"
 	"golang.org/x/exp/slices"
+	"golang.org/x/net/publicsuffix"
 
 	"github.com/gocolly/colly/v2"
 )
 
 type ScanResult struct {
-	visits                   uint32
-	googleAnalyticsScriptSrc bool
-	googleAnalyticsScript    bool
-	googleAnalyticsIFrame    bool
-	googleFontsLink          bool
-	googleFontsCss           []string
-	googleFontsStyle         []string
-	googleFontsScript        bool
-	otherLinks               []string
-	otherScripts             []string
-	otherIFrames             []string
-	otherCss                 []string
-	otherPreconnect          []string
-	otherStyle               []string
-	dnsPrefetch              bool
-	mu                       sync.Mutex
+	url                       string
+	scannedAt                 time.Time
+	reachable                 bool
+	visits                    atomic.Uint32
+	done                      uint32
+	totalBytes                int64
+	htmlBytes                 int64
+	cssBytes                  int64
+	savedFiles                int
+	progressAt                time.Time
+	googleAnalyticsScriptSrc  bool
+	googleAnalyticsScript     bool
+	googleAnalyticsIFrame     bool
+	googleAnalyticsScripts    []string
+	googleAnalyticsIFrames    []string
+	googleTagManagerScriptSrc bool
+	googleTagManagerScript    bool
+	googleTagManagerIFrame    bool
+	googleTagManagerScripts   []string
+	googleTagManagerIFrames   []string
+	googleFontsLink           bool
+	googleFontsLinks          []string
+	googleFontsCss            []string
+	googleFontsStyle          []string
+	googleFontsScript         bool
+	trackers                  []trackerFinding
+	matchedRules              []ruleMatch
+	otherLinks                []string
+	otherScripts              []string
+	otherIFrames              []string
+	otherCss                  []string
+	otherPreconnect           []string
+	otherPreload              []string
+	otherPrefetch             []string
+	otherStyle                []string
+	inlineStyleUrls           []string
+	formEndpoints             []formEndpoint
+	otherImages               []string
+	media                     []string
+	trackingPixels            []string
+	noscriptTrackers          []string
+	remoteFonts               []string
+	recaptcha                 []string
+	hcaptcha                  []string
+	consentScripts            []string
+	consentPlatform           string
+	scriptEndpoints           []string
+	workers                   []string
+	matomo                    []string
+	matomoScript              bool
+	isAmp                     bool
+	ampScripts                []string
+	ampComponents             []string
+	ampAnalytics              []string
+	platform                  string
+	pageInfo                  pageInfo
+	tls                       tlsInfo
+	tlsIssues                 []tlsIssue
+	inlineReferences          []string
+	socialEmbeds              []string
+	otherRedirects            []string
+	mixedContent              []mixedContent
+	clientRedirects           []clientRedirect
+	statusCounts              map[int]int
+	brokenPages               []brokenPage
+	redirects                 []redirect
+	redirectChain             []string
+	externalLinks             []string
+	externalChecks            []externalCheck
+	timings                   []requestTiming
+	cspOrigins                []cspOrigin
+	sriChecks                 []sriCheck
+	cookies                   []cookieInfo
+	retried                   []string
+	failed                    []string
+	deadHosts                 []string
+	dnsPrefetch               bool
+	hostVisits                map[string]int
+	pages                     []string
+	foundOn                   map[string][]string
+	foundBy                   map[string][]string
+	// agents are the User-Agents of -ua-list by the url of the page or
+	// resource fetched with them, a map of its own as requests are
+	// attributed in OnResponse before any lock is taken
+	agents sync.Map
+	mu     sync.Mutex
 }
 
+// cookieInfo describes a cookie set by a response
+type cookieInfo struct {
+	Name       string `json:"name"`
+	Domain     string `json:"domain"`
+	SetBy      string `json:"setBy"`
+	ThirdParty bool   `json:"thirdParty"`
+	Persistent bool   `json:"persistent"`
+}
+
+// persistentCookieAge is the lifetime from which a cookie counts as a
+// potential tracking cookie
+const persistentCookieAge = 30 * 24 * time.Hour
+
+// addCookies records the cookies of a Set-Cookie response header.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addCookies(r *colly.Response, domain string) {
+	header := http.Header{"Set-Cookie": r.Headers.Values("Set-Cookie")}
+	for _, cookie := range (&http.Response{Header: header}).Cookies() {
+		cookieDomain := strings.TrimPrefix(cookie.Domain, ".")
+		if cookieDomain == "" {
+			cookieDomain = r.Request.URL.Hostname()
+		}
+		info := cookieInfo{
+			Name:       cookie.Name,
+			Domain:     cookieDomain,
+			SetBy:      r.Request.URL.String(),
+			ThirdParty: !isSameDomain("//"+cookieDomain, domain),
+			Persistent: cookie.MaxAge > int(persistentCookieAge.Seconds()) ||
+				(cookie.MaxAge == 0 && time.Until(cookie.Expires) > persistentCookieAge),
+		}
+		known := slices.IndexFunc(scanResult.cookies, func(c cookieInfo) bool {
+			return c.Name == info.Name && c.Domain == info.Domain
+		})
+		if known < 0 {
+			scanResult.cookies = append(scanResult.cookies, info)
+			scanResult.emit("cookies", info.SetBy, "", info)
+		}
+	}
+}
+
+// addInlineReferences records the hosts of known 3rd party services
+// mentioned in inline code, like a <script> body or an onclick attribute.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addInlineReferences(code, where, domain string, page *url.URL) {
+	for _, host := range findTrackerHosts(code) {
+		if isSameDomain("//"+host, domain) {
+			continue
+		}
+		scanResult.add(&scanResult.inlineReferences, host, page.String())
+		scanResult.logger(page.String()).Info("3RD PARTY reference, unknown if that code executed", "in", where, "page", page, "host", host)
+	}
+}
+
+// add appends the resource to the list unless it is already in there and
+// remembers the page it was found on. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) add(list *[]string, resource, page string) {
+	if !slices.Contains(*list, resource) {
+		*list = append(*list, resource)
+	}
+	if scanResult.foundOn == nil {
+		scanResult.foundOn = map[string][]string{}
+	}
+	if !slices.Contains(scanResult.foundOn[resource], page) {
+		scanResult.foundOn[resource] = append(scanResult.foundOn[resource], page)
+		scanResult.emit(scanResult.listName(list), resource, page, nil)
+	}
+	if agent := scanResult.agent(page); agent != "" && !slices.Contains(scanResult.foundBy[resource], agent) {
+		if scanResult.foundBy == nil {
+			scanResult.foundBy = map[string][]string{}
+		}
+		scanResult.foundBy[resource] = append(scanResult.foundBy[resource], agent)
+	}
+}
+
+// jsonSchemaVersion is the version of the jsonResult shape documented in the
+// README. It has to be bumped whenever fields are added, renamed or removed.
+const jsonSchemaVersion = 24
+
+// jsonResult is the stable JSON representation of a ScanResult
+type jsonResult struct {
+	SchemaVersion             int                 `json:"schemaVersion"`
+	GeneratedAt               time.Time           `json:"generatedAt"`
+	Url                       string              `json:"url"`
+	Visits                    uint32              `json:"visits"`
+	TotalBytes                int64               `json:"totalBytes"`
+	HtmlBytes                 int64               `json:"htmlBytes"`
+	CssBytes                  int64               `json:"cssBytes"`
+	GoogleAnalyticsScriptSrc  bool                `json:"googleAnalyticsScriptSrc"`
+	GoogleAnalyticsScript     bool                `json:"googleAnalyticsScript"`
+	GoogleAnalyticsIFrame     bool                `json:"googleAnalyticsIFrame"`
+	GoogleTagManagerScriptSrc bool                `json:"googleTagManagerScriptSrc"`
+	GoogleTagManagerScript    bool                `json:"googleTagManagerScript"`
+	GoogleTagManagerIFrame    bool                `json:"googleTagManagerIFrame"`
+	GoogleFontsLink           bool                `json:"googleFontsLink"`
+	GoogleFontsCss            []string            `json:"googleFontsCss"`
+	GoogleFontsStyle          []string            `json:"googleFontsStyle"`
+	GoogleFontsScript         bool                `json:"googleFontsScript"`
+	Trackers                  []trackerFinding    `json:"trackers"`
+	MatchedRules              []ruleMatch         `json:"matchedRules"`
+	OtherLinks                []string            `json:"otherLinks"`
+	OtherScripts              []string            `json:"otherScripts"`
+	OtherIFrames              []string            `json:"otherIFrames"`
+	OtherCss                  []string            `json:"otherCss"`
+	OtherPreconnect           []string            `json:"otherPreconnect"`
+	OtherPreload              []string            `json:"otherPreload"`
+	OtherPrefetch             []string            `json:"otherPrefetch"`
+	OtherStyle                []string            `json:"otherStyle"`
+	InlineStyleUrls           []string            `json:"inlineStyleUrls"`
+	FormEndpoints             []formEndpoint      `json:"formEndpoints"`
+	OtherImages               []string            `json:"otherImages"`
+	Media                     []string            `json:"media"`
+	TrackingPixels            []string            `json:"trackingPixels"`
+	NoscriptTrackers          []string            `json:"noscriptTrackers"`
+	RemoteFonts               []string            `json:"remoteFonts"`
+	Recaptcha                 []string            `json:"recaptcha"`
+	Hcaptcha                  []string            `json:"hcaptcha"`
+	ConsentPlatform           string              `json:"consentPlatform"`
+	ConsentScripts            []string            `json:"consentScripts"`
+	ScriptEndpoints           []string            `json:"scriptEndpoints"`
+	Workers                   []string            `json:"workers"`
+	Matomo                    []string            `json:"matomo"`
+	MatomoScript              bool                `json:"matomoScript"`
+	IsAmp                     bool                `json:"isAmp"`
+	AmpScripts                []string            `json:"ampScripts"`
+	AmpComponents             []string            `json:"ampComponents"`
+	AmpAnalytics              []string            `json:"ampAnalytics"`
+	Platform                  string              `json:"platform"`
+	PageInfo                  pageInfo            `json:"pageInfo"`
+	TLS                       tlsInfo             `json:"tls"`
+	TLSIssues                 []tlsIssue          `json:"tlsIssues"`
+	PlatformResources         []string            `json:"platformResources"`
+	InlineReferences          []string            `json:"inlineReferences"`
+	SocialEmbeds              []string            `json:"socialEmbeds"`
+	OtherRedirects            []string            `json:"otherRedirects"`
+	MixedContent              []mixedContent      `json:"mixedContent"`
+	ClientRedirects           []clientRedirect    `json:"clientRedirects"`
+	Redirects                 []redirect          `json:"redirects"`
+	RedirectChain             []string            `json:"redirectChain"`
+	ExternalLinks             []externalCheck     `json:"externalLinks"`
+	CspOrigins                []cspOrigin         `json:"cspOrigins"`
+	Sri                       []sriCheck          `json:"sri"`
+	WithoutIntegrity          []string            `json:"withoutIntegrity"`
+	TimingsByType             []timingStats       `json:"timingsByType"`
+	TimingsByHost             []timingStats       `json:"timingsByHost"`
+	Approved                  []string            `json:"approved"`
+	Flagged                   []string            `json:"flagged"`
+	Compliance                *compliance         `json:"compliance"`
+	Cookies                   []cookieInfo        `json:"cookies"`
+	Retried                   []string            `json:"retried"`
+	Failed                    []string            `json:"failed"`
+	DeadHosts                 []string            `json:"deadHosts"`
+	StatusCounts              map[int]int         `json:"statusCounts"`
+	BrokenPages               []brokenPage        `json:"brokenPages"`
+	DnsPrefetch               bool                `json:"dnsPrefetch"`
+	FoundOn                   map[string][]string `json:"foundOn"`
+	FoundBy                   map[string][]string `json:"foundBy"`
+	Stats                     ScanStats           `json:"stats"`
+	Score                     int                 `json:"score"`
+	Grade                     string              `json:"grade"`
+}
+
+// build information, injected with -ldflags "-X main.version=..."
+var (
+	version = "dev"
+	commit  = "unknown"
+	date    = "unknown"
+)
+
 var (
-	verbose *bool
-	depth   *int
+	userAgent         *string
+	robotsUserAgent   *string
+	insecure          *bool
+	discoveryOrder    *bool
+	verbose           *bool
+	depth             *int
+	jsonOutput        *bool
+	summary           *bool
+	table             *bool
+	ignoreRobots      *bool
+	maxPages          *int
+	maxPagesPerHost   *int
+	listUrls          *bool
+	maxBody           *int
+	stream            *bool
+	saveDir           *string
+	cacheDir          *string
+	noCache           *bool
+	timings           *bool
+	otelEndpoint      *string
+	userAgents        []string
+	nextUserAgent     atomic.Uint32
+	timeout           *time.Duration
+	requestTimeout    *time.Duration
+	delay             *time.Duration
+	randomDelay       *time.Duration
+	parallelism       *int
+	concurrency       *int
+	checkExternal     *bool
+	scanScripts       *bool
+	importDepth       *int
+	requestSlots      chan struct{}
+	retries           *int
+	useSitemap        *bool
+	ignoreQuery       *bool
+	includeSubdomains *bool
+	localBaseUrl      *string
+	includePath       *regexp.Regexp
+	excludePath       *regexp.Regexp
+	basicAuth         *string
+	proxy             *string
+	headers           stringList
+	crawlDomains      stringList
+	paginate          stringList
+	cookieValues      stringList
+	fileCookies       []fileCookie
+	allowedDomains    stringList
+)
+
+// setupLogging sends the logs to stderr, findings and visited pages with -v
+// and the details of the crawl with -vv, only warnings otherwise
+func setupLogging(verbose, veryVerbose bool, format string) error {
+	options := &slog.HandlerOptions{Level: slog.LevelWarn}
+	if veryVerbose {
+		options.Level = slog.LevelDebug
+	} else if verbose {
+		options.Level = slog.LevelInfo
+	}
+	switch format {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+	default:
+		return fmt.Errorf("invalid value %q for -log-format, use text or json", format)
+	}
+	// slog takes over the log package as well, which would hide fatal
+	// errors below the log level
+	log.SetOutput(os.Stderr)
+	log.SetFlags(log.LstdFlags)
+	return nil
+}
+
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// parseHeaders turns "Name: Value" strings into a header map
+func parseHeaders(values []string) (http.Header, error) {
+	header := http.Header{}
+	for _, value := range values {
+		name, content, found := strings.Cut(value, ":")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: Value\"", value)
+		}
+		header.Add(name, strings.TrimSpace(content))
+	}
+	return header, nil
+}
+
+// nonNil makes sure empty lists are encoded as [] instead of null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+// newJsonResult converts a ScanResult to its JSON representation
+func newJsonResult(scanResult *ScanResult) jsonResult {
+	score := scanResult.score()
+	result := jsonResult{
+		SchemaVersion:             jsonSchemaVersion,
+		GeneratedAt:               time.Now().UTC(),
+		Url:                       scanResult.url,
+		Visits:                    scanResult.visits.Load(),
+		TotalBytes:                scanResult.totalBytes,
+		HtmlBytes:                 scanResult.htmlBytes,
+		CssBytes:                  scanResult.cssBytes,
+		GoogleAnalyticsScriptSrc:  scanResult.googleAnalyticsScriptSrc,
+		GoogleAnalyticsScript:     scanResult.googleAnalyticsScript,
+		GoogleAnalyticsIFrame:     scanResult.googleAnalyticsIFrame,
+		GoogleTagManagerScriptSrc: scanResult.googleTagManagerScriptSrc,
+		GoogleTagManagerScript:    scanResult.googleTagManagerScript,
+		GoogleTagManagerIFrame:    scanResult.googleTagManagerIFrame,
+		GoogleFontsLink:           scanResult.googleFontsLink,
+		GoogleFontsCss:            nonNil(scanResult.googleFontsCss),
+		GoogleFontsStyle:          nonNil(scanResult.googleFontsStyle),
+		GoogleFontsScript:         scanResult.googleFontsScript,
+		Trackers:                  scanResult.trackers,
+		MatchedRules:              scanResult.matchedRules,
+		OtherLinks:                nonNil(scanResult.otherLinks),
+		OtherScripts:              nonNil(scanResult.otherScripts),
+		OtherIFrames:              nonNil(scanResult.otherIFrames),
+		OtherCss:                  nonNil(scanResult.otherCss),
+		OtherPreconnect:           nonNil(scanResult.otherPreconnect),
+		OtherPreload:              nonNil(scanResult.otherPreload),
+		OtherPrefetch:             nonNil(scanResult.otherPrefetch),
+		OtherStyle:                nonNil(scanResult.otherStyle),
+		InlineStyleUrls:           nonNil(scanResult.inlineStyleUrls),
+		FormEndpoints:             scanResult.formEndpoints,
+		OtherImages:               nonNil(scanResult.otherImages),
+		Media:                     nonNil(scanResult.media),
+		TrackingPixels:            nonNil(scanResult.trackingPixels),
+		NoscriptTrackers:          nonNil(scanResult.noscriptTrackers),
+		RemoteFonts:               nonNil(scanResult.remoteFonts),
+		Recaptcha:                 nonNil(scanResult.recaptcha),
+		Hcaptcha:                  nonNil(scanResult.hcaptcha),
+		ConsentPlatform:           scanResult.consentPlatform,
+		ConsentScripts:            nonNil(scanResult.consentScripts),
+		ScriptEndpoints:           nonNil(scanResult.scriptEndpoints),
+		Workers:                   nonNil(scanResult.workers),
+		Matomo:                    nonNil(scanResult.matomo),
+		MatomoScript:              scanResult.matomoScript,
+		IsAmp:                     scanResult.isAmp,
+		AmpScripts:                nonNil(scanResult.ampScripts),
+		AmpComponents:             nonNil(scanResult.ampComponents),
+		AmpAnalytics:              nonNil(scanResult.ampAnalytics),
+		Platform:                  scanResult.platform,
+		PageInfo:                  scanResult.pageInfo,
+		TLS:                       scanResult.tls,
+		TLSIssues:                 scanResult.tlsIssues,
+		PlatformResources:         nonNil(scanResult.platformResources()),
+		InlineReferences:          nonNil(scanResult.inlineReferences),
+		SocialEmbeds:              nonNil(scanResult.socialEmbeds),
+		OtherRedirects:            nonNil(scanResult.otherRedirects),
+		MixedContent:              scanResult.mixedContent,
+		ClientRedirects:           scanResult.clientRedirects,
+		Redirects:                 scanResult.redirects,
+		RedirectChain:             nonNil(scanResult.redirectChain),
+		ExternalLinks:             scanResult.externalChecks,
+		Cookies:                   scanResult.cookies,
+		Retried:                   nonNil(scanResult.retried),
+		Failed:                    nonNil(scanResult.failed),
+		DeadHosts:                 nonNil(scanResult.deadHosts),
+		StatusCounts:              scanResult.statusCounts,
+		BrokenPages:               scanResult.brokenPages,
+		DnsPrefetch:               scanResult.dnsPrefetch,
+		FoundOn:                   scanResult.foundOn,
+		FoundBy:                   scanResult.foundBy,
+		Stats:                     scanResult.Stats(),
+		Score:                     score,
+		Grade:                     grade(score),
+		Sri:                       scanResult.sriChecks,
+		WithoutIntegrity:          nonNil(scanResult.withoutIntegrity()),
+	}
+	if len(allowedDomains) > 0 {
+		approved, flagged := scanResult.policyCheck()
+		result.Approved, result.Flagged = nonNil(approved), nonNil(flagged)
+		policy := scanResult.compliance()
+		result.Compliance = &policy
+	} else {
+		result.Approved, result.Flagged = []string{}, []string{}
+	}
+	if result.Cookies == nil {
+		result.Cookies = []cookieInfo{}
+	}
+	if result.FoundBy == nil {
+		result.FoundBy = map[string][]string{}
+	}
+	if result.Redirects == nil {
+		result.Redirects = []redirect{}
+	}
+	if result.MixedContent == nil {
+		result.MixedContent = []mixedContent{}
+	}
+	if result.ClientRedirects == nil {
+		result.ClientRedirects = []clientRedirect{}
+	}
+	if result.FormEndpoints == nil {
+		result.FormEndpoints = []formEndpoint{}
+	}
+	if result.TLSIssues == nil {
+		result.TLSIssues = []tlsIssue{}
+	}
+	if result.Sri == nil {
+		result.Sri = []sriCheck{}
+	}
+	if result.Trackers == nil {
+		result.Trackers = []trackerFinding{}
+	}
+	if result.MatchedRules == nil {
+		result.MatchedRules = []ruleMatch{}
+	}
+	if result.ExternalLinks == nil {
+		result.ExternalLinks = []externalCheck{}
+	}
+	if result.StatusCounts == nil {
+		result.StatusCounts = map[int]int{}
+	}
+	if result.BrokenPages == nil {
+		result.BrokenPages = []brokenPage{}
+	}
+	result.CspOrigins = scanResult.checkCspOrigins()
+	if result.CspOrigins == nil {
+		result.CspOrigins = []cspOrigin{}
+	}
+	result.TimingsByType, result.TimingsByHost = []timingStats{}, []timingStats{}
+	if *timings && len(scanResult.timings) > 0 {
+		result.TimingsByType = aggregateTimings(scanResult.timings, func(t requestTiming) string { return t.Type })
+		result.TimingsByHost = aggregateTimings(scanResult.timings, func(t requestTiming) string { return t.Host })
+	}
+	if result.FoundOn == nil {
+		result.FoundOn = map[string][]string{}
+	}
+	return result
+}
+
+func printJsonResult(w io.Writer, scanResult *ScanResult) {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(newJsonResult(scanResult)); err != nil {
+		log.Fatal("error encoding json result: ", err)
+	}
+}
+
+// resourceList is a list of 3rd party resources of the same kind
+type resourceList struct {
+	resourceType    string
+	urls            []string
+	googleAnalytics bool
+	googleFonts     bool
+	tagManager      bool
+}
+
+// resourceLists returns all 3rd party resources found on the website by kind
+func (scanResult *ScanResult) resourceLists() []resourceList {
+	return []resourceList{
+		{"script", scanResult.googleAnalyticsScripts, true, false, false},
+		{"iframe", scanResult.googleAnalyticsIFrames, true, false, false},
+		{"script", scanResult.googleTagManagerScripts, false, false, true},
+		{"iframe", scanResult.googleTagManagerIFrames, false, false, true},
+		{"link", scanResult.googleFontsLinks, false, true, false},
+		{"css-import", scanResult.googleFontsCss, false, true, false},
+		{"style-import", scanResult.googleFontsStyle, false, true, false},
+		{"link", scanResult.otherLinks, false, false, false},
+		{"script", scanResult.otherScripts, false, false, false},
+		{"iframe", scanResult.otherIFrames, false, false, false},
+		{"css-import", scanResult.otherCss, false, false, false},
+		{"style-import", scanResult.otherStyle, false, false, false},
+		{"inline-style", scanResult.inlineStyleUrls, false, false, false},
+		{"img", scanResult.otherImages, false, false, false},
+		{"media", scanResult.media, false, false, false},
+		{"tracking-pixel", scanResult.trackingPixels, false, false, false},
+		{"font", scanResult.remoteFonts, false, false, false},
+		{"recaptcha", scanResult.recaptcha, false, false, false},
+		{"hcaptcha", scanResult.hcaptcha, false, false, false},
+		{"consent", scanResult.consentScripts, false, false, false},
+		{"amp", scanResult.ampScripts, false, false, false},
+		{"preconnect", scanResult.otherPreconnect, false, false, false},
+		{"preload", scanResult.otherPreload, false, false, false},
+		{"prefetch", scanResult.otherPrefetch, false, false, false},
+		{"inline-reference", scanResult.inlineReferences, false, false, false},
+		{"script-endpoint", scanResult.scriptEndpoints, false, false, false},
+		{"worker", scanResult.workers, false, false, false},
+		{"social-embed", scanResult.socialEmbeds, false, false, false},
+		{"redirect", scanResult.otherRedirects, false, false, false},
+	}
+}
+
+// policyCheck splits all 3rd party resources into the ones approved by the
+// allowlist and the flagged ones
+func (scanResult *ScanResult) policyCheck() (approved, flagged []string) {
+	for _, list := range scanResult.resourceLists() {
+		for _, resource := range list.urls {
+			if isApproved(resource) {
+				approved = append(approved, resource)
+			} else {
+				flagged = append(flagged, resource)
+			}
+		}
+	}
+	return approved, flagged
+}
+
+// writeCsvRows writes one row per third party resource found on the website
+func writeCsvRows(w *csv.Writer, scanResult *ScanResult) error {
+	for _, r := range scanResult.resourceLists() {
+		for _, u := range r.urls {
+			err := w.Write([]string{
+				scanResult.url,
+				r.resourceType,
+				u,
+				strconv.FormatBool(r.googleAnalytics),
+				strconv.FormatBool(r.googleFonts),
+				strconv.FormatBool(r.tagManager),
+				strings.Join(scanResult.foundOn[u], " "),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	//colorGreen  = "\033[32m"
+	//colorBlue   = "\033[34m"
+	//colorPurple = "\033[35m"
+	//colorCyan   = "\033[36m"
+	//colorWhite  = "\033[37m"
 )
 
-func printResult(scanResult *ScanResult) {
-	colorReset := "\033[0m"
-	colorRed := "\033[31m"
-	colorYellow := "\033[33m"
-	//colorGreen := "\033[32m"
-	//colorBlue := "\033[34m"
-	//colorPurple := "\033[35m"
-	//colorCyan := "\033[36m"
-	//colorWhite := "\033[37m"
-
-	fmt.Printf(colorRed)
+// colorizer hands out ANSI escape sequences, or empty strings if color is disabled
+type colorizer bool
+
+func (c colorizer) color(code string) string {
+	if !c {
+		return ""
+	}
+	return code
+}
+
+// isTerminal reports whether the file is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// printList writes the resources on one line, or one per line with the pages
+// they were found on in verbose mode
+func printList(w io.Writer, scanResult *ScanResult, list []string) {
+	if !*verbose {
+		notes := make([]string, len(list))
+		for i, resource := range list {
+			notes[i] = resource + scanResult.platformNote(resource)
+		}
+		fmt.Fprintln(w, strings.Join(notes, ", "))
+		return
+	}
+	fmt.Fprintln(w)
+	for _, resource := range list {
+		fmt.Fprintf(w, "  %s%s\n", resource, scanResult.platformNote(resource))
+		for _, page := range scanResult.foundOn[resource] {
+			fmt.Fprintf(w, "    found on %s\n", page)
+		}
+	}
+}
+
+// printResult writes the human readable report, colored if useColor is set
+func printResult(w io.Writer, scanResult *ScanResult, useColor bool) {
+	color := colorizer(useColor).color
+
+	printScore(w, scanResult.score())
+	if scanResult.platform != "" {
+		fmt.Fprintf(w, "Website runs on %s, resources marked as %s default are loaded by the platform itself\n", scanResult.platform, scanResult.platform)
+	}
+	fmt.Fprint(w, color(colorRed))
 	if scanResult.googleAnalyticsScriptSrc {
-		fmt.Println("Website uses Google Analytics via <script src>")
+		fmt.Fprintln(w, "Website uses Google Analytics via <script src>")
 	}
 	if scanResult.googleAnalyticsIFrame {
-		fmt.Println("Website uses Google Analytics via <iframe>")
+		fmt.Fprintln(w, "Website uses Google Analytics via <iframe>")
+	}
+	if scanResult.googleTagManagerScriptSrc {
+		fmt.Fprintln(w, "Website uses Google Tag Manager via <script src>")
+	}
+	if scanResult.googleTagManagerIFrame {
+		fmt.Fprintln(w, "Website uses Google Tag Manager via <iframe>")
+	}
+	if scanResult.missingConsent() {
+		fmt.Fprintln(w, "Website loads trackers but no consent management platform was found")
+	}
+	if len(scanResult.mixedContent) > 0 {
+		fmt.Fprintln(w, "Website loads insecure http resources on https pages (mixed content):")
+		for _, finding := range scanResult.mixedContent {
+			fmt.Fprintf(w, "  %s on %s\n", finding.Url, finding.Page)
+		}
 	}
 	if scanResult.googleFontsLink {
-		fmt.Println("Website uses Google Fonts via <link>")
+		fmt.Fprintln(w, "Website uses Google Fonts via <link>")
 	}
 	if len(scanResult.googleFontsCss) > 0 {
-		fmt.Print("Website uses Google Fonts in css file @import: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.googleFontsCss[:], ", "))
-		fmt.Printf(colorRed)
+		fmt.Fprint(w, "Website uses Google Fonts in css file @import: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.googleFontsCss)
+		fmt.Fprint(w, color(colorRed))
 	}
 	if len(scanResult.googleFontsStyle) > 0 {
-		fmt.Print("Website uses Google Fonts in <style> @import: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.googleFontsStyle[:], ", "))
-		fmt.Printf(colorRed)
+		fmt.Fprint(w, "Website uses Google Fonts in <style> @import: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.googleFontsStyle)
+		fmt.Fprint(w, color(colorRed))
+	}
+	if len(scanResult.recaptcha) > 0 {
+		fmt.Fprint(w, "Website uses Google reCAPTCHA: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.recaptcha)
+		fmt.Fprint(w, color(colorRed))
+	}
+	if scanResult.isAmp || len(scanResult.ampScripts) > 0 {
+		fmt.Fprint(w, "Website uses AMP")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprintln(w, " (the AMP runtime is always loaded from Google):")
+		if len(scanResult.ampScripts) > 0 {
+			fmt.Fprint(w, "  scripts: ")
+			printList(w, scanResult, scanResult.ampScripts)
+		}
+		if len(scanResult.ampComponents) > 0 {
+			fmt.Fprintln(w, "  components:", strings.Join(scanResult.ampComponents, ", "))
+		}
+		if len(scanResult.ampAnalytics) > 0 {
+			fmt.Fprintln(w, "  amp-analytics:", strings.Join(scanResult.ampAnalytics, ", "))
+		}
+		fmt.Fprint(w, color(colorRed))
+	}
+	fmt.Fprint(w, color(colorReset))
+	if len(scanResult.trackers) > 0 {
+		fmt.Fprintln(w, "Trackers found:")
+		for _, tracker := range scanResult.trackers {
+			id := tracker.Id
+			if id == "" {
+				id = "unknown id"
+			}
+			fmt.Fprintf(w, "  %s %s (%s)\n", tracker.Tracker, id, strings.Join(tracker.Methods, ", "))
+		}
+	}
+	if len(scanResult.matchedRules) > 0 {
+		fmt.Fprintln(w, "Matched detection rules:")
+		for _, match := range scanResult.matchedRules {
+			resource := match.Resource
+			if resource == "" {
+				resource = "inline code on " + strings.Join(match.Pages, ", ")
+			}
+			fmt.Fprintf(w, "  %s (%s, %s): %s\n", match.Rule, match.Category, match.AppliesTo, resource)
+		}
 	}
-	fmt.Printf(colorReset)
 
-	fmt.Printf(colorYellow)
+	fmt.Fprint(w, color(colorYellow))
 	if scanResult.googleAnalyticsScript {
-		fmt.Print("Found Google Analytics URL in <script>")
-		fmt.Printf(colorReset)
-		fmt.Println(" (this doesn't imply that it gets executed)")
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found Google Analytics URL in <script>")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprintln(w, " (this doesn't imply that it gets executed)")
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if scanResult.googleTagManagerScript {
+		fmt.Fprint(w, "Found Google Tag Manager URL in <script>")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprintln(w, " (this doesn't imply that it gets executed)")
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if scanResult.googleFontsScript {
-		fmt.Print("Found Google Fonts URL in <script>")
-		fmt.Printf(colorReset)
-		fmt.Println(" (this doesn't imply that it gets executed)")
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found Google Fonts URL in <script>")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprintln(w, " (this doesn't imply that it gets executed)")
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.hcaptcha) > 0 {
+		fmt.Fprint(w, "Found hCaptcha: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.hcaptcha)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if scanResult.consentPlatform != "" {
+		fmt.Fprintf(w, "Found consent management platform %s", scanResult.consentPlatform)
+		fmt.Fprint(w, color(colorReset))
+		if len(scanResult.consentScripts) > 0 {
+			fmt.Fprint(w, ": ")
+			printList(w, scanResult, scanResult.consentScripts)
+		} else {
+			fmt.Fprintln(w, " (self-hosted or inline)")
+		}
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.inlineReferences) > 0 {
+		fmt.Fprint(w, "Found 3rd Party services in inline code")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprint(w, " (this doesn't imply that it gets executed): ")
+		printList(w, scanResult, scanResult.inlineReferences)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.matomo) > 0 || scanResult.matomoScript {
+		fmt.Fprintln(w, "Found analytics (self-hosted):")
+		fmt.Fprint(w, color(colorReset))
+		if len(scanResult.matomo) > 0 {
+			fmt.Fprint(w, "  Matomo: ")
+			printList(w, scanResult, scanResult.matomo)
+		} else {
+			fmt.Fprintln(w, "  Matomo tracking code in <script> (this doesn't imply that it gets executed)")
+		}
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.scriptEndpoints) > 0 {
+		fmt.Fprint(w, "Found 3rd Party endpoints called by scripts")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprint(w, " (heuristic, this doesn't imply that it gets executed): ")
+		printList(w, scanResult, scanResult.scriptEndpoints)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.workers) > 0 {
+		fmt.Fprint(w, "Found 3rd Party service workers and web workers started by scripts")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprint(w, " (heuristic, this doesn't imply that it gets executed): ")
+		printList(w, scanResult, scanResult.workers)
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherLinks) > 0 {
-		fmt.Print("Found 3rd Party <link> elements: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherLinks[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found 3rd Party <link> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherLinks)
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherScripts) > 0 {
-		fmt.Print("Found 3rd Party <script> elements: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherScripts[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprintln(w, "Found 3rd Party <script> elements:")
+		fmt.Fprint(w, color(colorReset))
+		groups := groupByCategory(scanResult.otherScripts)
+		for _, category := range serviceCategories {
+			if len(groups[category]) > 0 {
+				fmt.Fprintf(w, "  %s: ", category)
+				printList(w, scanResult, groups[category])
+			}
+		}
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherIFrames) > 0 {
-		fmt.Print("Found 3rd Party <iframe> elements: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherIFrames[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found 3rd Party <iframe> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherIFrames)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.otherRedirects) > 0 {
+		fmt.Fprint(w, "Found redirects to 3rd Party hosts: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherRedirects)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if redirects := scanResult.thirdPartyClientRedirects(); len(redirects) > 0 {
+		fmt.Fprint(w, "Found redirects to 3rd Party hosts by meta refresh or scripts")
+		fmt.Fprint(w, color(colorReset))
+		fmt.Fprint(w, " (scripts are a heuristic, this doesn't imply that they get executed): ")
+		printList(w, scanResult, redirects)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.socialEmbeds) > 0 {
+		fmt.Fprintln(w, "Found embedded videos and social media widgets:")
+		fmt.Fprint(w, color(colorReset))
+		for _, service := range embedServices {
+			var embeds []string
+			for _, embed := range scanResult.socialEmbeds {
+				if embedService(embed) == service.name {
+					embeds = append(embeds, embed)
+				}
+			}
+			if len(embeds) > 0 {
+				fmt.Fprintf(w, "  %s: ", service.name)
+				printList(w, scanResult, embeds)
+			}
+		}
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherCss) > 0 {
-		fmt.Print("Found 3rd Party @import in css: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherCss[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found 3rd Party @import in css: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherCss)
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherPreconnect) > 0 {
-		fmt.Print("Found 3rd Party <link rel='preconnect'> elements: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherPreconnect[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found 3rd Party <link rel='preconnect'> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherPreconnect)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.otherPreload) > 0 {
+		fmt.Fprint(w, "Found 3rd Party <link rel='preload'> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherPreload)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.otherPrefetch) > 0 {
+		fmt.Fprint(w, "Found 3rd Party <link rel='prefetch'> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherPrefetch)
+		fmt.Fprint(w, color(colorYellow))
 	}
 	if len(scanResult.otherStyle) > 0 {
-		fmt.Print("Found 3rd Party @import|s in <style> element: ")
-		fmt.Printf(colorReset)
-		fmt.Println(strings.Join(scanResult.otherStyle[:], ", "))
-		fmt.Printf(colorYellow)
+		fmt.Fprint(w, "Found 3rd Party @import|s in <style> element: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherStyle)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.formEndpoints) > 0 {
+		fmt.Fprintln(w, "Found 3rd Party <form> endpoints receiving user input:")
+		fmt.Fprint(w, color(colorReset))
+		for _, form := range scanResult.formEndpoints {
+			fmt.Fprintf(w, "  %s %s\n", form.Method, form.Url)
+			if *verbose {
+				for _, page := range form.Pages {
+					fmt.Fprintf(w, "    found on %s\n", page)
+				}
+			}
+		}
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.inlineStyleUrls) > 0 {
+		fmt.Fprint(w, "Found 3rd Party url()s in style attributes: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.inlineStyleUrls)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.otherImages) > 0 {
+		fmt.Fprint(w, "Found 3rd Party <img> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.otherImages)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.media) > 0 {
+		fmt.Fprint(w, "Found 3rd Party <source>, <video>, <audio> and <track> elements: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.media)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.trackingPixels) > 0 {
+		fmt.Fprint(w, "Found 3rd Party tracking pixels: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.trackingPixels)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.noscriptTrackers) > 0 {
+		fmt.Fprint(w, "Found trackers in <noscript> fallbacks: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.noscriptTrackers)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	if len(scanResult.remoteFonts) > 0 {
+		fmt.Fprint(w, "Found 3rd Party fonts: ")
+		fmt.Fprint(w, color(colorReset))
+		printList(w, scanResult, scanResult.remoteFonts)
+		fmt.Fprint(w, color(colorYellow))
+	}
+	fmt.Fprint(w, color(colorReset))
+
+	if len(scanResult.redirectChain) > 1 {
+		fmt.Fprintln(w, "Website redirects:", strings.Join(scanResult.redirectChain, " -> "))
+	}
+	if len(scanResult.externalChecks) > 0 {
+		fmt.Fprintln(w, "External links:")
+		for _, check := range scanResult.externalChecks {
+			if check.Error != "" {
+				fmt.Fprintf(w, "  %s (%s)\n", check.Url, check.Error)
+			} else if check.FinalUrl != check.Url {
+				fmt.Fprintf(w, "  %s -> %s (%d)\n", check.Url, check.FinalUrl, check.Status)
+			} else {
+				fmt.Fprintf(w, "  %s (%d)\n", check.Url, check.Status)
+			}
+		}
+	}
+	if missing := scanResult.withoutIntegrity(); len(missing) > 0 {
+		fmt.Fprintf(w, "3rd party scripts and stylesheets without Subresource Integrity (%d of %d):\n", len(missing), len(scanResult.sriChecks))
+		for _, u := range missing {
+			fmt.Fprintln(w, "  "+u)
+		}
+	}
+	if len(scanResult.cspOrigins) > 0 {
+		fmt.Fprintln(w, "Content-Security-Policy allows 3rd party origins:")
+		for _, origin := range scanResult.checkCspOrigins() {
+			loaded := "not loaded"
+			if origin.Loaded {
+				loaded = "loaded"
+			}
+			fmt.Fprintf(w, "  %s (%s, %s", origin.Origin, strings.Join(origin.Directives, " "), loaded)
+			if origin.ReportOnly {
+				fmt.Fprint(w, ", report only")
+			}
+			fmt.Fprintln(w, ")")
+		}
+	}
+	if len(allowedDomains) > 0 {
+		approved, flagged := scanResult.policyCheck()
+		if len(approved) > 0 {
+			fmt.Fprint(w, "Approved 3rd Party resources: ")
+			printList(w, scanResult, approved)
+		}
+		if len(flagged) > 0 {
+			fmt.Fprint(w, color(colorRed), "Flagged 3rd Party resources, not on the allowlist: ", color(colorReset))
+			printList(w, scanResult, flagged)
+		}
+		printCompliance(w, scanResult.compliance(), color)
 	}
-	fmt.Printf(colorReset)
 
 	if scanResult.dnsPrefetch {
-		fmt.Println("Found <link rel='dns-prefetch'> elements")
+		fmt.Fprintln(w, "Found <link rel='dns-prefetch'> elements")
+	}
+	if len(scanResult.cookies) > 0 {
+		fmt.Fprintln(w, "Cookies set by the website:")
+		for _, cookie := range scanResult.cookies {
+			party := "1st party"
+			if cookie.ThirdParty {
+				party = "3rd party"
+			}
+			fmt.Fprintf(w, "  %s (%s, %s", cookie.Name, cookie.Domain, party)
+			if cookie.Persistent {
+				fmt.Fprint(w, ", ", color(colorYellow), "persistent", color(colorReset))
+			}
+			fmt.Fprintln(w, ")")
+		}
+	}
+	printPageInfo(w, scanResult.pageInfo)
+	printTLS(w, scanResult.tls, scanResult.tlsIssues, color)
+	if len(scanResult.statusCounts) > 0 {
+		printStatusCounts(w, scanResult.statusCounts)
+	}
+	if len(scanResult.brokenPages) > 0 {
+		fmt.Fprintln(w, color(colorRed)+"Broken pages of the website:"+color(colorReset))
+		for _, page := range scanResult.brokenPages {
+			fmt.Fprintf(w, "  %s (%d)\n", page.Url, page.Status)
+		}
+	}
+	if len(scanResult.failed) > 0 {
+		fmt.Fprintln(w, "Pages which could not be loaded:")
+		for _, page := range scanResult.failed {
+			fmt.Fprintln(w, "  "+page)
+		}
+	}
+	if len(scanResult.deadHosts) > 0 {
+		fmt.Fprintln(w, "Hosts which could not be resolved:", strings.Join(scanResult.deadHosts, ", "))
+	}
+	if *timings && len(scanResult.timings) > 0 {
+		printTimings(w, "Response times by type:", aggregateTimings(scanResult.timings, func(t requestTiming) string { return t.Type }))
+		printTimings(w, "Response times by host:", aggregateTimings(scanResult.timings, func(t requestTiming) string { return t.Host }))
+	}
+	printStats(w, scanResult.Stats())
+	if scanResult.savedFiles > 0 {
+		fmt.Fprintf(w, "Saved %d files to %s\n", scanResult.savedFiles, *saveDir)
+	}
+	if scanResult.totalBytes > 0 {
+		otherBytes := scanResult.totalBytes - scanResult.htmlBytes - scanResult.cssBytes
+		fmt.Fprintf(w, "Downloaded %s (html %s, css %s, other %s)\n", formatBytes(scanResult.totalBytes),
+			formatBytes(scanResult.htmlBytes), formatBytes(scanResult.cssBytes), formatBytes(otherBytes))
+	}
+}
+
+// formatBytes formats a size in bytes human readable, like 1.5 MB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size := float64(n) / unit
+	prefix := 0
+	for size >= unit && prefix < 2 {
+		size /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", size, "KMG"[prefix])
+}
+
+// printSummary writes a one line verdict for the website
+func printSummary(w io.Writer, scanResult *ScanResult) {
+	yesNo := func(definitely, maybe bool) string {
+		if definitely {
+			return "yes"
+		}
+		if maybe {
+			return "maybe"
+		}
+		return "no"
+	}
+	googleAnalytics := yesNo(scanResult.googleAnalyticsScriptSrc || scanResult.googleAnalyticsIFrame, scanResult.googleAnalyticsScript)
+	tagManager := yesNo(scanResult.googleTagManagerScriptSrc || scanResult.googleTagManagerIFrame, scanResult.googleTagManagerScript)
+	googleFonts := yesNo(scanResult.googleFontsLink || len(scanResult.googleFontsCss) > 0 || len(scanResult.googleFontsStyle) > 0, scanResult.googleFontsScript)
+	matomo := yesNo(len(scanResult.matomo) > 0, scanResult.matomoScript)
+	consent := yesNo(scanResult.consentPlatform != "", false)
+
+	scripts := len(scanResult.otherScripts) + len(scanResult.ampScripts) + len(scanResult.consentScripts)
+	iframes := len(scanResult.otherIFrames) + len(scanResult.socialEmbeds)
+	links := len(scanResult.otherLinks) + len(scanResult.otherPreconnect) + len(scanResult.otherPreload) + len(scanResult.otherPrefetch)
+	imports := len(scanResult.otherCss) + len(scanResult.otherStyle)
+	images := len(scanResult.otherImages) + len(scanResult.trackingPixels) + len(scanResult.inlineStyleUrls)
+	media := len(scanResult.media)
+	fonts := len(scanResult.remoteFonts)
+	total := scripts + iframes + links + imports + images + media + fonts
+
+	fmt.Fprintf(w, "%s: GA=%s GTM=%s GFonts=%s Matomo=%s CMP=%s 3rd-party=%d (scripts=%d iframes=%d links=%d imports=%d images=%d media=%d fonts=%d)",
+		scanResult.url, googleAnalytics, tagManager, googleFonts, matomo, consent, total, scripts, iframes, links, imports, images, media, fonts)
+	score := scanResult.score()
+	fmt.Fprintf(w, " score=%d grade=%s", score, grade(score))
+	if scanResult.platform != "" {
+		fmt.Fprintf(w, " platform=%s", scanResult.platform)
+	}
+	if len(scanResult.mixedContent) > 0 {
+		fmt.Fprintf(w, " mixed=%d", len(scanResult.mixedContent))
+	}
+	if redirects := scanResult.thirdPartyClientRedirects(); len(redirects) > 0 {
+		fmt.Fprintf(w, " client-redirects=%d", len(redirects))
+	}
+	if len(allowedDomains) > 0 {
+		fmt.Fprintf(w, " compliance=%.1f%%", scanResult.compliance().Percent)
+	}
+	if *saveDir != "" {
+		fmt.Fprintf(w, " saved=%d", scanResult.savedFiles)
+	}
+	fmt.Fprintln(w)
+}
+
+// severity ranks findings, its value is used as exit code with -fail-on
+type severity int
+
+const (
+	severityNone       severity = 0
+	severityThirdParty severity = 2
+	severityFonts      severity = 3
+	severityAnalytics  severity = 4
+	// severityFlagged is not ranked with the findings above, it is only
+	// used as exit code with -fail-on flagged
+	severityFlagged severity = 5
+)
+
+// failOnLevels maps the values of the -fail-on flag to the lowest severity failing the run
+var failOnLevels = map[string]severity{
+	"none":            severityNone,
+	"any-third-party": severityThirdParty,
+	"fonts":           severityFonts,
+	"ga":              severityAnalytics,
+	"flagged":         severityFlagged,
+}
+
+// worstFinding returns the severity of the worst finding of the scan
+func (scanResult *ScanResult) worstFinding() severity {
+	if scanResult.googleAnalyticsScriptSrc || scanResult.googleAnalyticsIFrame ||
+		scanResult.googleTagManagerScriptSrc || scanResult.googleTagManagerIFrame {
+		return severityAnalytics
+	}
+	if scanResult.googleFontsLink || len(scanResult.googleFontsCss) > 0 || len(scanResult.googleFontsStyle) > 0 {
+		return severityFonts
+	}
+	for _, list := range [][]string{
+		scanResult.otherLinks,
+		scanResult.otherScripts,
+		scanResult.otherIFrames,
+		scanResult.otherCss,
+		scanResult.otherPreconnect,
+		scanResult.otherPreload,
+		scanResult.otherPrefetch,
+		scanResult.otherStyle,
+		scanResult.inlineStyleUrls,
+		scanResult.otherImages,
+		scanResult.media,
+		scanResult.trackingPixels,
+		scanResult.remoteFonts,
+		scanResult.recaptcha,
+		scanResult.hcaptcha,
+		scanResult.consentScripts,
+		scanResult.ampScripts,
+		scanResult.socialEmbeds,
+		scanResult.otherRedirects,
+		scanResult.thirdPartyClientRedirects(),
+	} {
+		if len(list) > 0 {
+			return severityThirdParty
+		}
 	}
+	return severityNone
+}
+
+// progressInterval is the minimum time between two progress line updates
+const progressInterval = 200 * time.Millisecond
+
+// showProgress reports whether the live progress line should be printed
+func showProgress() bool {
+	return !*verbose && !*jsonOutput && !*summary && !*listUrls && !*stream && *concurrency <= 1 && isTerminal(os.Stderr)
 }
 
-func printProgress(count uint32) {
+// printProgress redraws the progress line with visited and pending pages and
+// the elapsed time, at most once per progressInterval unless forced. With a
+// page limit a bar and an estimate of the remaining time are shown as well.
+// The caller must hold scanResult.mu.
+func printProgress(scanResult *ScanResult, force bool) {
+	now := time.Now()
+	if !force && now.Sub(scanResult.progressAt) < progressInterval {
+		return
+	}
+	scanResult.progressAt = now
+	elapsed := now.Sub(scanResult.scannedAt)
+	visits := scanResult.visits.Load()
+
+	line := fmt.Sprintf("%d pages visited, %d pending, %s elapsed",
+		visits, visits-scanResult.done, formatDuration(elapsed))
+	if *maxPages > 0 {
+		const width = 20
+		filled := int(scanResult.done) * width / *maxPages
+		if filled > width {
+			filled = width
+		}
+		line = fmt.Sprintf("[%s%s] %s", strings.Repeat("=", filled), strings.Repeat(" ", width-filled), line)
+		if scanResult.done > 0 && int(scanResult.done) < *maxPages {
+			remaining := elapsed / time.Duration(scanResult.done) * time.Duration(*maxPages-int(scanResult.done))
+			line += fmt.Sprintf(", ETA %s", formatDuration(remaining))
+		}
+	}
+
 	removeLine := "\033[2K"
+	fmt.Fprint(os.Stderr, removeLine)
+	fmt.Fprint(os.Stderr, "\r")
+	fmt.Fprint(os.Stderr, line)
+}
+
+// formatDuration formats d as minutes and seconds, like 1:05
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// gtagAnalyticsRegexp matches gtag.js loading a Google Analytics property
+var gtagAnalyticsRegexp = regexp.MustCompile(`googletagmanager\.com/gtag/js\?(.*&)?id=(G|UA)-`)
+
+// The hosts of Google services, matched as part of urls. They can be
+// replaced with the keys of the same name in the -config file.
+var (
+	// googleAnalyticsHosts serve Google Analytics and collect its data
+	googleAnalyticsHosts = []string{
+		"google-analytics.com",
+		"analytics.google.com",
+		"stats.g.doubleclick.net",
+	}
+	// googleTagManagerHosts serve Google Tag Manager
+	googleTagManagerHosts = []string{
+		"googletagmanager.com",
+	}
+	// googleFontsHosts serve the css and the fonts of Google Fonts, the .cn
+	// hosts are used in China and themes.googleusercontent.com by old
+	// embeds
+	googleFontsHosts = []string{
+		"fonts.googleapis.com",
+		"fonts.gstatic.com",
+		"fonts.google.com",
+		"fonts.googleapis.cn",
+		"fonts.gstatic.cn",
+		"themes.googleusercontent.com",
+	}
+)
+
+// containsHost reports whether the url or code contains one of the hosts
+func containsHost(s string, hosts []string) bool {
+	for _, host := range hosts {
+		if strings.Contains(s, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGoogleAnalyticsUrl reports whether the url loads Google Analytics directly
+func isGoogleAnalyticsUrl(u string) bool {
+	return containsHost(u, googleAnalyticsHosts) || gtagAnalyticsRegexp.MatchString(u)
+}
+
+// isGoogleTagManagerUrl reports whether the url belongs to Google Tag Manager,
+// e.g. gtm.js or its <noscript> iframe ns.html
+func isGoogleTagManagerUrl(u string) bool {
+	return containsHost(u, googleTagManagerHosts)
+}
 
-	fmt.Printf(removeLine)
-	fmt.Printf("\r")
-	fmt.Printf("%d pages visited", count)
+// isGoogleFontsUrl reports whether the url belongs to Google Fonts
+func isGoogleFontsUrl(u string) bool {
+	return containsHost(u, googleFontsHosts)
 }
 
-func isSameDomain(url, baseUrl, domain string) bool {
-	// regex should match all possible relative paths
-	localLink, err := regexp.MatchString("^(/?[a-zA-Z0-9-_.]+)*([#?].*)?$", url)
+// isSameDomain reports whether a reference found on a page of domain points
+// to the website itself. Relative references and references without a host
+// like data: or mailto: count as same domain, urls with a host are compared
+// by it whatever their scheme, like ws: or ftp:. "www." is ignored and with
+// -include-subdomains all hosts of the same registrable domain are accepted.
+func isSameDomain(ref, domain string) bool {
+	u, err := url.Parse(strings.TrimSpace(ref))
 	if err != nil {
-		log.Fatal("error using regex in `isSameDomain`")
+		return false
 	}
-	if localLink {
-		return true
+	if u.Host == "" {
+		return u.Scheme != "http" && u.Scheme != "https"
 	}
-	if strings.HasPrefix(url, "//"+domain) {
-		return true
+
+	// the domains added with -crawl-domain belong to the website as well
+	for _, d := range append([]string{domain}, crawlDomains...) {
+		if isDomainHost(u.Hostname(), d) {
+			return true
+		}
 	}
-	if strings.HasPrefix(url, baseUrl) {
+	return false
+}
+
+// isDomainHost reports whether the host is the domain, ignoring "www.", or
+// with -include-subdomains shares its registrable domain
+func isDomainHost(host, domain string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	domain = strings.TrimPrefix(strings.ToLower(domain), "www.")
+	if host == domain {
 		return true
 	}
-	if url == "about:blank" {
-		return true
+	if *includeSubdomains {
+		hostRoot, err := publicsuffix.EffectiveTLDPlusOne(host)
+		if err != nil {
+			return false
+		}
+		domainRoot, err := publicsuffix.EffectiveTLDPlusOne(domain)
+		return err == nil && hostRoot == domainRoot
 	}
 	return false
 }
 
-func checkUrl(urlString string) {
-	// match multiple @import styles
-	cssRegexp, err := regexp.Compile(`@import\W?(url)?\(?['"]?([^\)"']*)['"]?\)?`)
-	if err != nil {
-		log.Fatal("error compiling regexp in checkUrl()")
+// isApproved reports whether the host of a 3rd party resource (or a bare
+// host) is one of the allowed domains or a subdomain of one
+func isApproved(resource string) bool {
+	host := resource
+	if u, err := url.Parse(resource); err == nil && u.Host != "" {
+		host = u.Hostname()
 	}
-	u, err := url.Parse(urlString)
-	if err != nil {
-		log.Fatal("error compiling regexp in checkUrl()")
+	host = strings.ToLower(host)
+	for _, domain := range allowedDomains {
+		domain = strings.ToLower(domain)
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
 	}
-	domain := u.Hostname()
+	return false
+}
 
-	protocol := strings.Split(urlString, ":")[0]
-	if protocol != "https" && protocol != "http" {
-		protocol = "https" // default if none defined
+// readUrlFile reads one url per line, skipping blank lines and # comments
+func readUrlFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer file.Close()
 
-	baseUrl := protocol + "://" + domain
-	if u.Port() != "" {
-		baseUrl += ":" + u.Port()
+	var urls []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
 	}
+	return urls, scanner.Err()
+}
 
-	fmt.Println("crawling", urlString)
+// fontServices are hosts serving web fonts besides Google Fonts
+var fontServices = []string{
+	"use.typekit.net",
+	"p.typekit.net",
+	"fonts.bunny.net",
+	"fast.fonts.net",
+	"cloud.typography.com",
+	"use.fontawesome.com",
+}
 
-	var scanResult ScanResult
+// matomoCallRegexp matches the inline tracking code of Matomo, formerly Piwik
+var matomoCallRegexp = regexp.MustCompile(`_paq\.push\(|(Matomo|Piwik)\.getTracker`)
 
-	c := colly.NewCollector(
-		colly.AllowedDomains(domain),
-		colly.MaxDepth(*depth),
-		colly.Async(true),
-	)
+// isMatomoUrl reports whether the url loads the Matomo or Piwik tracker
+func isMatomoUrl(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	name := strings.ToLower(path.Base(parsed.Path))
+	return name == "matomo.js" || name == "piwik.js"
+}
+
+// isAmpUrl reports whether the url loads the AMP runtime or a component
+func isAmpUrl(u string) bool {
+	return strings.Contains(u, "cdn.ampproject.org")
+}
+
+// captchaList returns the list of the scan result a captcha url belongs to,
+// or nil if the url is no captcha
+func captchaList(scanResult *ScanResult, u string) *[]string {
+	switch {
+	case strings.Contains(u, "google.com/recaptcha/"),
+		strings.Contains(u, "gstatic.com/recaptcha/"),
+		strings.Contains(u, "recaptcha.net/recaptcha/"):
+		return &scanResult.recaptcha
+	case strings.Contains(u, "hcaptcha.com/"):
+		return &scanResult.hcaptcha
+	}
+	return nil
+}
+
+// isFontServiceUrl reports whether the url belongs to a known web font service
+func isFontServiceUrl(u string) bool {
+	return containsHost(u, fontServices)
+}
+
+var (
+	fontFaceRegexp = regexp.MustCompile(`(?s)@font-face\s*\{([^}]*)\}`)
+	cssUrlRegexp   = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+)
+
+// findFontFaceUrls returns the resolved urls of all fonts declared with
+// @font-face in the css, skipping inline data: fonts
+func findFontFaceUrls(css string, base *url.URL) []string {
+	var urls []string
+	for _, block := range fontFaceRegexp.FindAllStringSubmatch(css, -1) {
+		for _, m := range cssUrlRegexp.FindAllStringSubmatch(block[1], -1) {
+			if strings.HasPrefix(m[1], "data:") {
+				continue
+			}
+			urls = append(urls, resolveUrl(base, m[1]))
+		}
+	}
+	return urls
+}
+
+// resolveUrl resolves a possibly relative reference against the url of the
+// resource it was found in
+func resolveUrl(base *url.URL, ref string) string {
+	refUrl, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(refUrl).String()
+}
+
+// documentBase returns the url relative references on the page of e resolve
+// against, which is the <base href> of the page if it has one
+func documentBase(e *colly.HTMLElement) *url.URL {
+	base, err := url.Parse(e.Request.AbsoluteURL(""))
+	if err != nil {
+		return e.Request.URL
+	}
+	return base
+}
+
+// parseSrcset returns the urls of a srcset attribute like "a.png 1x, b.png 2x"
+// or "a.png 480w,b.png 800w". As in browsers urls may contain commas, only
+// commas after a url or its width or density descriptor separate candidates.
+func parseSrcset(srcset string) []string {
+	const whitespace = " \t\n\r\f"
+	var urls []string
+	rest := srcset
+	for {
+		rest = strings.TrimLeft(rest, whitespace+",")
+		if rest == "" {
+			return urls
+		}
+		end := strings.IndexAny(rest, whitespace)
+		if end < 0 {
+			end = len(rest)
+		}
+		candidate := rest[:end]
+		rest = rest[end:]
+		if u := strings.TrimRight(candidate, ","); u != candidate {
+			// a url followed by a comma has no descriptors
+			urls = append(urls, u)
+			continue
+		}
+		urls = append(urls, candidate)
+		// skip the descriptors up to the next comma outside of parentheses
+		depth := 0
+		end = len(rest)
+		for i, r := range rest {
+			if r == '(' {
+				depth++
+			} else if r == ')' && depth > 0 {
+				depth--
+			} else if r == ',' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		rest = rest[end:]
+	}
+}
+
+// isPixelSize reports whether the width and height attributes of an image
+// describe a 0 or 1 pixel image, commonly used for tracking
+func isPixelSize(width, height string) bool {
+	if width == "" && height == "" {
+		return false
+	}
+	isPixel := func(v string) bool {
+		v = strings.TrimSuffix(strings.TrimSpace(v), "px")
+		return v == "" || v == "0" || v == "1"
+	}
+	return isPixel(width) && isPixel(height)
+}
+
+// isCss reports whether the response is a stylesheet, either by its
+// Content-Type or by the .css extension of the path
+func isCss(r *colly.Response) bool {
+	contentType := r.Headers.Get("Content-Type")
+	if strings.HasPrefix(strings.ToLower(contentType), "text/css") {
+		return true
+	}
+	return strings.HasSuffix(strings.ToLower(r.Request.URL.Path), ".css")
+}
+
+// isPathInScope applies the -include and -exclude filters to a path
+func isPathInScope(path string) bool {
+	if includePath != nil && !includePath.MatchString(path) {
+		return false
+	}
+	if excludePath != nil && excludePath.MatchString(path) {
+		return false
+	}
+	return true
+}
+
+// isTransient reports whether a failed request is worth retrying. Hosts
+// which don't resolve are not, even if the lookup timed out.
+func isTransient(r *colly.Response, err error) bool {
+	if isDnsError(err) {
+		return false
+	}
+	if r.StatusCode >= 500 {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isDnsError reports whether a request failed as its host could not be
+// resolved
+func isDnsError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// addDeadHost remembers a host which could not be resolved, further
+// requests to it are skipped. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addDeadHost(host string) {
+	if !slices.Contains(scanResult.deadHosts, host) {
+		scanResult.deadHosts = append(scanResult.deadHosts, host)
+	}
+}
+
+// visit follows a link found on a page, reporting urls blocked by robots.txt
+func visit(e *colly.HTMLElement, href string) {
+	if *depth == 0 {
+		return
+	}
+	absolute := e.Request.AbsoluteURL(href)
+	if absolute == "" {
+		return
+	}
+	absolute = normalizeUrl(absolute)
+	err := e.Request.Visit(absolute)
+	if errors.Is(err, colly.ErrRobotsTxtBlocked) {
+		slog.Debug("SKIPPED by robots.txt", "page", e.Request.URL.String(), "url", absolute)
+	}
+}
+
+// fetchResource requests a stylesheet or script of a page to analyze its
+// content. Resources are no pages, so they are requested outside of the
+// crawl with their own context and regardless of the depth.
+// The caller must not hold scanResult.mu, colly fetches robots.txt of a
+// new host right away through the transport, whose callbacks take it.
+func fetchResource(c *colly.Collector, e *colly.HTMLElement, href, kind string) {
+	absolute := e.Request.AbsoluteURL(href)
+	if absolute == "" {
+		return
+	}
+	ctx := colly.NewContext()
+	ctx.Put("resource", kind)
+	ctx.Put("agent", agentOf(e.Request))
+	requestResource(c, ctx, absolute, e.Request.URL)
+}
+
+// fetchImport requests a stylesheet of the website imported with @import
+// in a stylesheet of the given import level, unless -import-depth is reached.
+// Like for fetchResource the caller must not hold scanResult.mu.
+// Loops of imports end as colly requests each url only once.
+func fetchImport(c *colly.Collector, u string, from *colly.Request, level int) {
+	if level >= *importDepth {
+		return
+	}
+	ctx := colly.NewContext()
+	ctx.Put("resource", "stylesheet")
+	ctx.Put("importLevel", level+1)
+	ctx.Put("agent", agentOf(from))
+	requestResource(c, ctx, u, from.URL)
+}
+
+// requestResource requests a resource found on the page from with its own
+// context
+func requestResource(c *colly.Collector, ctx *colly.Context, absolute string, from *url.URL) {
+	err := c.Request(http.MethodGet, normalizeUrl(absolute), nil, ctx, nil)
+	if errors.Is(err, colly.ErrRobotsTxtBlocked) {
+		slog.Debug("SKIPPED by robots.txt", "page", from.String(), "url", absolute)
+	}
+}
+
+// isTruncated reports whether the body of a response was cut off at
+// -max-body. colly cuts off bodies at the limit, so a body of that size is
+// most likely incomplete.
+func isTruncated(r *colly.Response) bool {
+	return *maxBody > 0 && len(r.Body) >= *maxBody
+}
+
+// resourceKind returns the kind of resource a request made by fetchResource
+// is for, or an empty string for pages
+func resourceKind(r *colly.Request) string {
+	return r.Ctx.Get("resource")
+}
+
+// hasRel reports whether the rel attribute of a link contains the link type
+func hasRel(e *colly.HTMLElement, linkType string) bool {
+	for _, rel := range strings.Fields(e.Attr("rel")) {
+		if strings.EqualFold(rel, linkType) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackingParams are query parameters which don't change the content of a page
+var trackingParams = []string{"fbclid", "gclid", "dclid", "msclkid", "mc_cid", "mc_eid", "_ga"}
+
+// normalizeUrl strips the fragment, trailing slashes and tracking parameters
+// (or the whole query with -ignore-query) so each page is only visited once
+func normalizeUrl(rawUrl string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return rawUrl
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if strings.HasSuffix(u.Path, "/") {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if *ignoreQuery {
+		u.RawQuery = ""
+	} else if u.RawQuery != "" {
+		query := u.Query()
+		for param := range query {
+			if strings.HasPrefix(param, "utm_") || slices.Contains(trackingParams, param) {
+				query.Del(param)
+			}
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
+
+// contextTransport cancels all requests in flight once its context is done
+type contextTransport struct {
+	ctx  context.Context
+	base http.RoundTripper
+}
+
+func (t *contextTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.base.RoundTrip(req.WithContext(t.ctx))
+}
+
+// limitTransport caps the number of requests in flight across all websites
+// scanned in parallel. A slot is taken until the response body is closed.
+type limitTransport struct {
+	base  http.RoundTripper
+	slots chan struct{}
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	select {
+	case t.slots <- struct{}{}:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	release := func() { <-t.slots }
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return resp, err
+	}
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: release}
+	return resp, nil
+}
+
+// newTransport returns the transport for all requests, routed through the
+// -proxy flag or the HTTP_PROXY/HTTPS_PROXY environment variables
+func newTransport() (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if *insecure {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	if *proxy == "" {
+		transport.Proxy = http.ProxyFromEnvironment
+		return transport, nil
+	}
+	proxyUrl, err := url.Parse(*proxy)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing proxy url: %w", err)
+	}
+	switch proxyUrl.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, use http, https or socks5", proxyUrl.Scheme)
+	}
+	transport.Proxy = http.ProxyURL(proxyUrl)
+	return transport, nil
+}
+
+// agentOf returns the User-Agent of -ua-list a request is attributed to, or
+// "" without -ua-list. Resources are attributed to the agent of the page
+// they were found on, which fetchResource stores in their own context.
+// Pages share the context of the page linking them, so theirs is the
+// header they were sent with.
+func agentOf(r *colly.Request) string {
+	if len(userAgents) == 0 {
+		return ""
+	}
+	if resourceKind(r) != "" {
+		return r.Ctx.Get("agent")
+	}
+	return r.Headers.Get("User-Agent")
+}
+
+// agent returns the User-Agent of -ua-list the page or resource was fetched
+// for, or "" without -ua-list
+func (scanResult *ScanResult) agent(page string) string {
+	agent, _ := scanResult.agents.Load(page)
+	s, _ := agent.(string)
+	return s
+}
+
+// logger returns the logger of the findings on a page, naming the
+// User-Agent of -ua-list the page was fetched with
+func (scanResult *ScanResult) logger(page string) *slog.Logger {
+	if agent := scanResult.agent(page); agent != "" {
+		return slog.With("userAgent", agent)
+	}
+	return slog.Default()
+}
+
+// setRequestHeaders sets the headers of -header, -ua-list and -basic-auth
+func setRequestHeaders(header, extraHeaders http.Header) {
+	for name, values := range extraHeaders {
+		header.Del(name)
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	if len(userAgents) > 0 {
+		n := nextUserAgent.Add(1) - 1
+		header.Set("User-Agent", userAgents[int(n)%len(userAgents)])
+	}
+	if *basicAuth != "" {
+		username, password, _ := strings.Cut(*basicAuth, ":")
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+	}
+}
+
+// seedFromSitemap visits all pages of the website listed in its sitemap.xml.
+// The sitemap is requested like the pages, with the headers and cookies of
+// the website, and only from the crawled hosts.
+func seedFromSitemap(ctx context.Context, c *colly.Collector, transport http.RoundTripper, baseUrl string, allowedHosts []string, extraHeaders http.Header) {
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !slices.Contains(allowedHosts, req.URL.Hostname()) {
+				return fmt.Errorf("redirect to %s outside of the crawled hosts", req.URL)
+			}
+			return checkRedirect(req, via)
+		},
+	}
+	prepare := func(req *http.Request) {
+		req.Header.Set("User-Agent", *userAgent)
+		setRequestHeaders(req.Header, extraHeaders)
+		for _, cookie := range c.Cookies(req.URL.String()) {
+			req.AddCookie(cookie)
+		}
+	}
+	pages, err := fetchSitemap(ctx, client, baseUrl+"/sitemap.xml", allowedHosts, prepare)
+	if err != nil {
+		slog.Debug("NO SITEMAP, crawling links only", "error", err)
+		return
+	}
+	for _, page := range pages {
+		u, err := url.Parse(page)
+		if err != nil || !slices.Contains(allowedHosts, u.Hostname()) {
+			slog.Debug("SKIPPED sitemap url outside of the crawled hosts", "url", page)
+			continue
+		}
+		c.Visit(page)
+	}
+}
+
+// parseSeedUrl parses a url given to scan, defaulting to https if it has no
+// scheme. Only http and https urls with a host are accepted.
+func parseSeedUrl(urlString string) (*url.URL, error) {
+	urlString = strings.TrimSpace(urlString)
+	if !strings.Contains(urlString, "://") {
+		urlString = "https://" + urlString
+	}
+	u, err := url.Parse(urlString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url %s: %w", urlString, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid url %s: unsupported scheme %q, use http or https", urlString, u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid url %s: missing host", urlString)
+	}
+	return u, nil
+}
+
+func checkUrl(ctx context.Context, urlString string) (*ScanResult, error) {
+	extraHeaders, err := parseHeaders(headers)
+	if err != nil {
+		return nil, err
+	}
+	extraCookies, err := parseCookies(cookieValues)
+	if err != nil {
+		return nil, err
+	}
+	source := urlString
+	local := isLocalSource(source)
+	var localPage []byte
+	if local {
+		if localPage, err = readLocalPage(source); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", source, err)
+		}
+		urlString = *localBaseUrl
+		if urlString == "" {
+			urlString = defaultLocalBaseUrl
+		}
+	}
+	// match multiple @import styles
+	cssRegexp, err := regexp.Compile(`@import\W?(url)?\(?['"]?([^\)"']*)['"]?\)?`)
+	if err != nil {
+		log.Fatal("error compiling regexp in checkUrl()")
+	}
+	u, err := parseSeedUrl(urlString)
+	if err != nil {
+		return nil, err
+	}
+	domain := u.Hostname()
+	baseUrl := (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String()
+
+	scanResult := ScanResult{url: source, scannedAt: time.Now(), hostVisits: map[string]int{}}
+	seedUrl := normalizeUrl(u.String())
+
+	// a depth of 0 scans the given page only, while colly would treat it as unlimited
+	maxDepth := *depth
+	if maxDepth == 0 {
+		maxDepth = 1
+	}
+
+	// colly follows links to these hosts only, subdomains like www. are
+	// hosts of their own
+	allowedHosts := append([]string{domain}, crawlDomains...)
+	scope := []any{"allowedDomains", strings.Join(allowedHosts, ","), "baseUrl", baseUrl, "seed", seedUrl, "depth", *depth}
+	if includePath != nil {
+		scope = append(scope, "include", includePath.String())
+	}
+	if excludePath != nil {
+		scope = append(scope, "exclude", excludePath.String())
+	}
+	slog.Info("SCOPE", scope...)
+
+	c := colly.NewCollector(
+		colly.AllowedDomains(allowedHosts...),
+		colly.MaxDepth(maxDepth),
+		colly.Async(true),
+		colly.UserAgent(*userAgent),
+		colly.MaxBodySize(*maxBody),
+	)
+	// colly matches robots.txt groups against the agent of the collector
+	if *robotsUserAgent != "" {
+		c.UserAgent = *robotsUserAgent
+	}
+	c.IgnoreRobotsTxt = *ignoreRobots || local
+	if *cacheDir != "" && !*noCache && !local {
+		c.CacheDir = *cacheDir
+	}
+	err = c.Limit(&colly.LimitRule{
+		DomainGlob:  "*",
+		Delay:       *delay,
+		RandomDelay: *randomDelay,
+		Parallelism: *parallelism,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error setting request limits: %w", err)
+	}
+	c.SetRequestTimeout(*requestTimeout)
+	// the spans of the requests of the scan are children of its root span,
+	// which is in the context the transport sends them with
+	ctx, scanSpan := startScanSpan(ctx, seedUrl)
+	defer scanSpan.End()
+	transport, err := newTransport()
+	if err != nil {
+		return nil, err
+	}
+	var seedTLS struct {
+		mu   sync.Mutex
+		info tlsInfo
+	}
+	if local {
+		c.WithTransport(&localTransport{pageUrl: seedUrl, body: localPage})
+	} else {
+		// the TLS state has a lock of its own, as colly requests robots.txt
+		// through the transport while handlers hold scanResult.mu
+		var base http.RoundTripper = &tlsTransport{base: transport, onTLS: func(host string, state *tls.ConnectionState) {
+			seedTLS.mu.Lock()
+			defer seedTLS.mu.Unlock()
+			if host == domain && seedTLS.info.Host == "" {
+				seedTLS.info = newTLSInfo(host, state)
+			}
+		}}
+		if requestSlots != nil {
+			base = &limitTransport{base: base, slots: requestSlots}
+		}
+		// spans start once a request is sent, not in OnRequest, as colly
+		// runs all OnRequest callbacks of requests which are aborted
+		if *otelEndpoint != "" {
+			base = &traceTransport{base: base}
+		}
+		if *timings {
+			base = &timingTransport{base: base, onTiming: func(timing requestTiming) {
+				scanResult.mu.Lock()
+				defer scanResult.mu.Unlock()
+				scanResult.timings = append(scanResult.timings, timing)
+			}}
+		}
+		c.WithTransport(&contextTransport{ctx: ctx, base: &decompressTransport{base: &redirectTransport{
+			base: base,
+			onRedirect: func(from, to *url.URL, status int) {
+				scanResult.mu.Lock()
+				defer scanResult.mu.Unlock()
+				scanResult.addRedirect(from, to, domain)
+				scanResult.addStatus(status, from, false, domain)
+				slog.Debug("REDIRECT", "from", from.String(), "to", to.String())
+			},
+		}}})
+		c.SetRedirectHandler(checkRedirect)
+	}
+	// cookies are set as host-only cookies of the website, so the jar
+	// sends them with all its requests but never to other hosts
+	if cookies := append(cookiesFor(fileCookies, domain), extraCookies...); len(cookies) > 0 {
+		if err := c.SetCookies(baseUrl, cookies); err != nil {
+			return nil, fmt.Errorf("error setting cookies: %w", err)
+		}
+	}
+
+	// Find and visit all links
+	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		visit(e, e.Attr("href"))
+		if *checkExternal && !*listUrls {
+			if href := resolveUrl(documentBase(e), e.Attr("href")); !isSameDomain(href, domain) {
+				scanResult.mu.Lock()
+				scanResult.addExternalLink(href)
+				scanResult.mu.Unlock()
+			}
+		}
+	})
+
+	c.OnRequest(func(r *colly.Request) {
+		if ctx.Err() != nil {
+			r.Abort()
+			return
+		}
+		// a host which didn't resolve once fails right away
+		scanResult.mu.Lock()
+		dead := slices.Contains(scanResult.deadHosts, r.URL.Hostname())
+		scanResult.mu.Unlock()
+		if dead {
+			slog.Debug("SKIPPED, host could not be resolved", "url", r.URL.String())
+			r.Abort()
+			return
+		}
+		// with -robots-ua the agent of the collector is only the robots.txt
+		// token, requests are still sent with the User-Agent of -ua
+		if *robotsUserAgent != "" {
+			r.Headers.Set("User-Agent", *userAgent)
+		}
+		setRequestHeaders(*r.Headers, extraHeaders)
+		// a local page is analyzed on its own, without following any links
+		if local && r.URL.String() != seedUrl {
+			r.Abort()
+			return
+		}
+		// resources don't count as visits and are never filtered, they
+		// are needed to analyze the pages
+		if kind := resourceKind(r); kind != "" {
+			slog.Debug("FETCHING", "kind", kind, "url", r.URL.String())
+			return
+		}
+		if r.URL.String() != seedUrl && !isPathInScope(r.URL.Path) {
+			slog.Debug("SKIPPED, path filtered", "url", r.URL.String())
+			r.Abort()
+			return
+		}
+		if attempt, _ := r.Ctx.GetAny("attempt").(int); attempt > 0 {
+			slog.Debug("VISITING again", "url", r.URL.String())
+			return
+		}
+		// the visit is counted up front and taken back if a limit is hit,
+		// so the page limit holds without locking scanResult.mu
+		if visits := scanResult.visits.Add(1); *maxPages > 0 && visits > uint32(*maxPages) {
+			scanResult.visits.Add(^uint32(0))
+			slog.Debug("SKIPPED, page limit reached", "url", r.URL.String())
+			r.Abort()
+			return
+		}
+		host := r.URL.Hostname()
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		if *maxPagesPerHost > 0 && scanResult.hostVisits[host] >= *maxPagesPerHost {
+			scanResult.visits.Add(^uint32(0))
+			slog.Debug("SKIPPED, host limit reached", "url", r.URL.String())
+			r.Abort()
+			return
+		}
+		scanResult.hostVisits[host] += 1
+		if len(userAgents) > 0 {
+			slog.Info("VISITING", "url", r.URL.String(), "userAgent", r.Headers.Get("User-Agent"))
+		} else {
+			slog.Info("VISITING", "url", r.URL.String())
+		}
+		if showProgress() {
+			printProgress(&scanResult, false)
+		}
+	})
+
+	c.OnScraped(func(r *colly.Response) {
+		if resourceKind(r.Request) != "" {
+			return
+		}
+		// failed pages never get here, cut off ones are not analyzed
+		if !isTruncated(r) {
+			metrics.addPageVisit()
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.done += 1
+		if showProgress() {
+			printProgress(&scanResult, false)
+		}
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		attempt, _ := r.Ctx.GetAny("attempt").(int)
+		if attempt < *retries && ctx.Err() == nil && isTransient(r, err) {
+			scanResult.mu.Lock()
+			if !slices.Contains(scanResult.retried, r.Request.URL.String()) {
+				scanResult.retried = append(scanResult.retried, r.Request.URL.String())
+			}
+			scanResult.mu.Unlock()
+
+			backoff := time.Second << attempt
+			slog.Debug("RETRYING", "in", backoff, "url", r.Request.URL.String(), "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+				r.Ctx.Put("attempt", attempt+1)
+				r.Request.Retry()
+				return
+			}
+		}
+
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		if resourceKind(r.Request) == "" {
+			scanResult.done += 1
+		}
+		// requests cut off by a stopped crawl didn't fail on their own
+		if ctx.Err() != nil {
+			return
+		}
+		// redirects to 3rd party hosts are not followed, they are reported
+		// as findings instead of failures
+		if scanResult.redirectsToThirdParty(r.Request.URL.String()) {
+			slog.Debug("NOT FOLLOWING redirect to 3rd party host", "url", r.Request.URL.String())
+			return
+		}
+		scanResult.addStatus(r.StatusCode, r.Request.URL, resourceKind(r.Request) == "", domain)
+		if isDnsError(err) {
+			scanResult.addDeadHost(r.Request.URL.Hostname())
+		}
+		if issue := certificateIssue(err); issue != "" {
+			scanResult.addTLSIssue(r.Request.URL.Hostname(), issue)
+		}
+		scanResult.failed = append(scanResult.failed, fmt.Sprintf("%s (%v)", r.Request.URL, err))
+		slog.Info("FAILED", "url", r.Request.URL.String(), "error", err)
+		if showProgress() {
+			printProgress(&scanResult, false)
+		}
+	})
+
+	c.OnHTML("link[href]", func(e *colly.HTMLElement) {
+		if *listUrls {
+			return
+		}
+		href := resolveUrl(documentBase(e), e.Attr("href"))
+		// only stylesheets are fetched, icons, manifests, feeds and other
+		// links are no pages to crawl
+		if hasRel(e, "stylesheet") && analyzes(analysisFonts, analysisCss) {
+			fetchResource(c, e, href, "stylesheet")
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.matchRules(ruleLink, href, e.Request.URL.String())
+		thirdParty := !isSameDomain(href, domain)
+
+		if e.Attr("rel") == "dns-prefetch" {
+			if !analyzes(analysisLinks) {
+				return
+			}
+			scanResult.dnsPrefetch = true
+			scanResult.logger(e.Request.URL.String()).Info("DNS-PREFETCH", "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", e.Attr("rel"), "id", e.Attr("id"))
+			return
+		}
+
+		if e.Attr("rel") == "preconnect" && thirdParty {
+			if !analyzes(analysisLinks) {
+				return
+			}
+			scanResult.add(&scanResult.otherPreconnect, href, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("LINK / PRECONNECT", "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", e.Attr("rel"), "id", e.Attr("id"))
+			return
+		}
+
+		if isGoogleFontsUrl(href) {
+			if !analyzes(analysisFonts) {
+				return
+			}
+			scanResult.googleFontsLink = true
+			scanResult.add(&scanResult.googleFontsLinks, href, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("LINK / GOOGLEFONT", "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", e.Attr("rel"), "id", e.Attr("id"))
+			return
+		}
+
+		if isFontServiceUrl(href) {
+			if !analyzes(analysisFonts) {
+				return
+			}
+			scanResult.add(&scanResult.remoteFonts, href, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("LINK / FONT", "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", e.Attr("rel"), "id", e.Attr("id"))
+			return
+		}
+
+		// resource hints make the browser contact the host before the
+		// resource is used, modulepreload counts as preload
+		if rel := e.Attr("rel"); thirdParty && (rel == "preload" || rel == "modulepreload" || rel == "prefetch") {
+			if !analyzes(analysisLinks) {
+				return
+			}
+			list := &scanResult.otherPreload
+			if rel == "prefetch" {
+				list = &scanResult.otherPrefetch
+			}
+			scanResult.add(list, href, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("LINK / "+strings.ToUpper(rel), "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", rel, "as", e.Attr("as"), "id", e.Attr("id"))
+			return
+		}
+
+		if thirdParty && analyzes(analysisLinks) {
+			scanResult.add(&scanResult.otherLinks, href, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("3RD PARTY LINK", "page", e.Request.URL.String(), "url", e.Attr("href"), "rel", e.Attr("rel"), "id", e.Attr("id"))
+			return
+		}
+	})
+
+	c.OnHTML("script", func(e *colly.HTMLElement) {
+		if *listUrls {
+			return
+		}
+		// the script is fetched once the lock is released, see fetchResource
+		fetch := ""
+		defer func() {
+			if fetch != "" {
+				fetchResource(c, e, fetch, "script")
+			}
+		}()
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		src := e.Attr("src")
+
+		if src != "" {
+			src = resolveUrl(documentBase(e), src)
+			scanResult.matchRules(ruleScriptSrc, src, e.Request.URL.String())
+			thirdParty := !isSameDomain(src, domain)
+			if isGoogleAnalyticsUrl(src) {
+				if !analyzes(analysisAnalytics) {
+					return
+				}
+				scanResult.googleAnalyticsScriptSrc = true
+				scanResult.addTrackers(src, "src", "Google Analytics", e.Request.URL.String())
+				scanResult.add(&scanResult.googleAnalyticsScripts, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE ANALYTICS <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if isGoogleTagManagerUrl(src) {
+				if !analyzes(analysisAnalytics) {
+					return
+				}
+				scanResult.googleTagManagerScriptSrc = true
+				scanResult.addTrackers(src, "src", "Google Tag Manager", e.Request.URL.String())
+				scanResult.add(&scanResult.googleTagManagerScripts, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE TAG MANAGER <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if isAmpUrl(src) {
+				if !analyzes(analysisScripts) {
+					return
+				}
+				scanResult.add(&scanResult.ampScripts, src, e.Request.URL.String())
+				component := e.Attr("custom-element")
+				if component == "" {
+					component = e.Attr("custom-template")
+				}
+				if component != "" && !slices.Contains(scanResult.ampComponents, component) {
+					scanResult.ampComponents = append(scanResult.ampComponents, component)
+				}
+				scanResult.logger(e.Request.URL.String()).Info("AMP <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			// Matomo is mostly self-hosted, so it is analytics of the
+			// website itself rather than a 3rd party resource
+			if isMatomoUrl(src) && !thirdParty {
+				if !analyzes(analysisAnalytics) {
+					return
+				}
+				scanResult.add(&scanResult.matomo, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("MATOMO <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if captcha := captchaList(&scanResult, src); captcha != nil {
+				if !analyzes(analysisScripts) {
+					return
+				}
+				scanResult.add(captcha, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("CAPTCHA <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if consent := consentPlatformOfUrl(src); consent != "" && thirdParty {
+				if !analyzes(analysisAnalytics) {
+					return
+				}
+				scanResult.setConsentPlatform(consent, src, e.Request.URL.String())
+				scanResult.add(&scanResult.consentScripts, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("CONSENT <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if thirdParty && analyzes(analysisScripts) {
+				scanResult.add(&scanResult.otherScripts, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("3RD PARTY <script>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if *scanScripts && analyzes(analysisScripts) {
+				fetch = src
+			}
+		}
+		scanResult.matchRules(ruleInline, e.Text, e.Request.URL.String())
+		for _, location := range findScriptLocations(e.Text, documentBase(e)) {
+			scanResult.addClientRedirect(location, viaScript, domain, e.Request.URL)
+		}
+		if analyzes(analysisScripts) {
+			scanResult.addScriptEndpoints(e.Text, "<script>", domain, documentBase(e), e.Request.URL.String())
+			scanResult.addWorkers(e.Text, "<script>", domain, documentBase(e), e.Request.URL.String())
+		}
+		if analyzes(analysisAnalytics) {
+			scanResult.addInlineReferences(e.Text, "<script>", domain, e.Request.URL)
+			scanResult.setConsentPlatform(consentPlatformOfCode(e.Text), "inline", e.Request.URL.String())
+			if isGoogleAnalyticsUrl(e.Text) || isGoogleTagManagerUrl(e.Text) || strings.Contains(e.Text, "gtag(") {
+				scanResult.addTrackers(e.Text, "inline", "", e.Request.URL.String())
+			}
+			if isGoogleAnalyticsUrl(e.Text) {
+				scanResult.googleAnalyticsScript = true
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE ANALYTICS URL in <script>, unknown if that code executed", "page", e.Request.URL.String())
+				return
+			}
+			if matomoCallRegexp.MatchString(e.Text) {
+				scanResult.matomoScript = true
+				scanResult.logger(e.Request.URL.String()).Info("MATOMO tracking code in <script>, unknown if that code executed", "page", e.Request.URL.String())
+			}
+			if isGoogleTagManagerUrl(e.Text) {
+				scanResult.googleTagManagerScript = true
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE TAG MANAGER URL in <script>, unknown if that code executed", "page", e.Request.URL.String())
+				return
+			}
+		}
+		if isGoogleFontsUrl(e.Text) && analyzes(analysisFonts) {
+			scanResult.googleFontsScript = true
+			scanResult.logger(e.Request.URL.String()).Info("GOOGLE FONTS URL in <script>, unknown if that code executed", "page", e.Request.URL.String())
+		}
+	})
+
+	// AMP pages are marked by an amp or ⚡ attribute of the html element
+	c.OnHTML("html", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisScripts) {
+			return
+		}
+		for _, attr := range e.DOM.Nodes[0].Attr {
+			if attr.Key == "amp" || attr.Key == "⚡" {
+				scanResult.mu.Lock()
+				scanResult.isAmp = true
+				scanResult.mu.Unlock()
+				scanResult.logger(e.Request.URL.String()).Info("AMP page", "page", e.Request.URL.String())
+				return
+			}
+		}
+	})
 
-	// Find and visit all links
-	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
-		e.Request.Visit(e.Attr("href"))
+	// the platform of the website shows in the urls of its resources or in
+	// the meta generator tag
+	c.OnHTML(`script[src], link[href], img[src], meta[name="generator"]`, func(e *colly.HTMLElement) {
+		if *listUrls {
+			return
+		}
+		page := e.Request.URL.String()
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		if e.Name == "meta" {
+			scanResult.setPlatform(platformOfGenerator(e.Attr("content")), "generator "+e.Attr("content"), page)
+			return
+		}
+		u := e.Request.AbsoluteURL(e.Attr("src") + e.Attr("href"))
+		scanResult.setPlatform(platformOfUrl(u), u, page)
 	})
 
-	c.OnRequest(func(r *colly.Request) {
+	// the meta tags of the seed page describe it in the report, the page it
+	// redirects to if it does, not pages of the sitemap or -paginate
+	c.OnHTML("meta", func(e *colly.HTMLElement) {
+		if *listUrls || resourceKind(e.Request) != "" {
+			return
+		}
 		scanResult.mu.Lock()
 		defer scanResult.mu.Unlock()
-		scanResult.visits += 1
-		if !*verbose {
-			printProgress(scanResult.visits)
-		} else {
-			fmt.Println("VISITING:", r.URL)
+		chain := scanResult.followRedirects(seedUrl)
+		if normalizeUrl(e.Request.URL.String()) == normalizeUrl(chain[len(chain)-1]) {
+			scanResult.pageInfo.Url = e.Request.URL.String()
+			scanResult.pageInfo.addMeta(e)
 		}
 	})
 
-	c.OnHTML("link[href]", func(e *colly.HTMLElement) {
+	// pages can redirect in the browser, which no http redirect shows
+	c.OnHTML(`meta[http-equiv]`, func(e *colly.HTMLElement) {
+		if *listUrls || resourceKind(e.Request) != "" || !strings.EqualFold(e.Attr("http-equiv"), "refresh") {
+			return
+		}
+		target := metaRefreshUrl(e.Attr("content"))
+		if target == "" {
+			return
+		}
 		scanResult.mu.Lock()
 		defer scanResult.mu.Unlock()
-		href := e.Attr("href")
-		e.Request.Visit(href)
-		thirdParty := !isSameDomain(href, baseUrl, domain)
+		scanResult.addClientRedirect(resolveUrl(documentBase(e), target), viaMetaRefresh, domain, e.Request.URL)
+	})
 
-		if e.Attr("rel") == "dns-prefetch" {
-			scanResult.dnsPrefetch = true
-			if *verbose {
-				fmt.Printf("DNS-PREFETCH on %s: %s, rel: %s, id: %s\n", e.Request.URL, e.Attr("href"), e.Attr("rel"), e.Attr("id"))
-			}
+	// browsers block scripts, stylesheets and iframes loaded over http by
+	// https pages and warn about such images and media
+	c.OnHTML(`script[src], link[rel~="stylesheet"][href], iframe[src], img[src], source[src], video[src], audio[src]`, func(e *colly.HTMLElement) {
+		if *listUrls {
 			return
 		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.addMixedContent(resolveUrl(documentBase(e), e.Attr("src")+e.Attr("href")), e.Request.URL)
+	})
 
-		if e.Attr("rel") == "preconnect" && thirdParty {
-			if !slices.Contains(scanResult.otherPreconnect, href) {
-				scanResult.otherPreconnect = append(scanResult.otherPreconnect, href)
-			}
-			if *verbose {
-				fmt.Printf("LINK / PRECONNECT on %s: %s, rel: %s, id: %s\n", e.Request.URL, e.Attr("href"), e.Attr("rel"), e.Attr("id"))
-			}
+	// 3rd party scripts and stylesheets without an integrity hash run
+	// whatever their host serves
+	c.OnHTML(`script[src], link[rel~="stylesheet"][href]`, func(e *colly.HTMLElement) {
+		if *listUrls || (e.Name == "script" && !analyzes(analysisScripts)) || (e.Name == "link" && !analyzes(analysisLinks)) {
+			return
+		}
+		u := resolveUrl(documentBase(e), e.Attr("src")+e.Attr("href"))
+		if isSameDomain(u, domain) {
 			return
 		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.addSriCheck(u, e.Name, strings.TrimSpace(e.Attr("integrity")), e.Attr("crossorigin"))
+	})
 
-		if strings.Contains(href, "fonts.googleapis.com") || strings.Contains(href, "fonts.gstatic.com") {
-			scanResult.googleFontsLink = true
-			if *verbose {
-				fmt.Printf("LINK / GOOGLEFONT on %s: %s, rel: %s, id: %s\n", e.Request.URL, e.Attr("href"), e.Attr("rel"), e.Attr("id"))
-			}
+	// amp-analytics sends data to the vendor given by its type, or to the
+	// endpoints of its own configuration
+	c.OnHTML("amp-analytics", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisAnalytics) {
 			return
 		}
+		vendor := e.Attr("type")
+		if vendor == "" {
+			vendor = "custom"
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		if !slices.Contains(scanResult.ampAnalytics, vendor) {
+			scanResult.ampAnalytics = append(scanResult.ampAnalytics, vendor)
+		}
+		scanResult.logger(e.Request.URL.String()).Info("AMP-ANALYTICS", "page", e.Request.URL.String(), "type", vendor)
+	})
 
-		if thirdParty {
-			if !slices.Contains(scanResult.otherLinks, href) {
-				scanResult.otherLinks = append(scanResult.otherLinks, href)
-			}
-			if *verbose {
-				fmt.Printf("3RD PARTY LINK on %s: %s, rel: %s, id: %s\n", e.Request.URL, e.Attr("href"), e.Attr("rel"), e.Attr("id"))
-			}
+	// event handler attributes like onclick can embed tracking code as well
+	c.OnHTML("*", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisAnalytics) {
 			return
 		}
+		for _, attr := range e.DOM.Nodes[0].Attr {
+			if !strings.HasPrefix(strings.ToLower(attr.Key), "on") {
+				continue
+			}
+			scanResult.mu.Lock()
+			scanResult.addInlineReferences(attr.Val, attr.Key+" attribute", domain, e.Request.URL)
+			scanResult.mu.Unlock()
+		}
 	})
 
-	c.OnHTML("script", func(e *colly.HTMLElement) {
+	onIFrame := func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisAnalytics, analysisIFrames) {
+			return
+		}
 		scanResult.mu.Lock()
 		defer scanResult.mu.Unlock()
 		src := e.Attr("src")
 
 		if src != "" {
-			thirdParty := !isSameDomain(src, baseUrl, domain)
-			if strings.Contains(src, "googletagmanager.com") {
-				scanResult.googleAnalyticsScriptSrc = true
-				if *verbose {
-					fmt.Printf("GOOGLE ANALYTICS <script> sourced on %s: %s\n", e.Request.URL, src)
+			src = resolveUrl(documentBase(e), src)
+			scanResult.matchRules(ruleIFrame, src, e.Request.URL.String())
+			thirdParty := !isSameDomain(src, domain)
+			if isGoogleAnalyticsUrl(src) {
+				if !analyzes(analysisAnalytics) {
+					return
 				}
+				scanResult.googleAnalyticsIFrame = true
+				scanResult.addTrackers(src, "iframe", "Google Analytics", e.Request.URL.String())
+				scanResult.add(&scanResult.googleAnalyticsIFrames, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE ANALYTICS <iframe>", "page", e.Request.URL.String(), "url", src)
 				return
 			}
-			if thirdParty {
-				if !slices.Contains(scanResult.otherScripts, src) {
-					scanResult.otherScripts = append(scanResult.otherScripts, src)
+			if isGoogleTagManagerUrl(src) {
+				if !analyzes(analysisAnalytics) {
+					return
 				}
-				if *verbose {
-					fmt.Printf("3RD PARTY <script> sourced on %s: %s\n", e.Request.URL, src)
+				scanResult.googleTagManagerIFrame = true
+				scanResult.addTrackers(src, "iframe", "Google Tag Manager", e.Request.URL.String())
+				scanResult.add(&scanResult.googleTagManagerIFrames, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("GOOGLE TAG MANAGER <iframe>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if captcha := captchaList(&scanResult, src); captcha != nil {
+				if !analyzes(analysisIFrames) {
+					return
 				}
+				scanResult.add(captcha, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("CAPTCHA <iframe>", "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if service := embedService(src); service != "" && thirdParty && analyzes(analysisIFrames) {
+				scanResult.add(&scanResult.socialEmbeds, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("EMBED <iframe>", "service", service, "page", e.Request.URL.String(), "url", src)
+				return
+			}
+			if thirdParty && analyzes(analysisIFrames) {
+				scanResult.add(&scanResult.otherIFrames, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("3RD PARTY <iframe>", "page", e.Request.URL.String(), "url", src)
 				return
 			}
 		}
-		if strings.Contains(e.Text, "googletagmanager.com") {
-			scanResult.googleAnalyticsScript = true
-			if *verbose {
-				fmt.Printf("GOOGLE ANALYTICS URL found in <script> on %s (unknown if that code executed)\n", e.Request.URL)
+	}
+	c.OnHTML("iframe[src]", onIFrame)
+
+	onImage := func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisAnalytics, analysisImages) {
+			return
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		pixel := isPixelSize(e.Attr("width"), e.Attr("height"))
+
+		for _, src := range append([]string{e.Attr("src")}, parseSrcset(e.Attr("srcset"))...) {
+			if src == "" || strings.HasPrefix(src, "data:") {
+				continue
+			}
+			src = resolveUrl(documentBase(e), src)
+			if isSameDomain(src, domain) {
+				continue
 			}
+			if pixel {
+				if !analyzes(analysisAnalytics) {
+					continue
+				}
+				scanResult.add(&scanResult.trackingPixels, src, e.Request.URL.String())
+				scanResult.logger(e.Request.URL.String()).Info("3RD PARTY tracking pixel", "page", e.Request.URL.String(), "url", src)
+				continue
+			}
+			if !analyzes(analysisImages) {
+				continue
+			}
+			scanResult.add(&scanResult.otherImages, src, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("3RD PARTY <img>", "page", e.Request.URL.String(), "url", src)
+		}
+	}
+	c.OnHTML("img[src], img[srcset]", onImage)
+
+	// trackers place a pixel or iframe in <noscript> for browsers without
+	// JavaScript, its content is only text and parsed on its own
+	c.OnHTML("noscript", func(e *colly.HTMLElement) {
+		if *listUrls {
 			return
 		}
-		if strings.Contains(e.Text, "fonts.googleapis.com") {
-			scanResult.googleFontsScript = true
-			if *verbose {
-				fmt.Printf("GOOGLE FONTS URL found in <script> on %s (unknown if that code is executed)\n", e.Request.URL)
+		for _, element := range noscriptElements(e, "iframe[src], img[src], img[srcset]") {
+			if element.Name == "iframe" {
+				onIFrame(element)
+			} else {
+				onImage(element)
 			}
+			src := element.Attr("src")
+			if src == "" || !analyzes(analysisAnalytics) {
+				continue
+			}
+			src = resolveUrl(documentBase(element), src)
+			if isSameDomain(src, domain) || !isNoscriptTracker(element, src) {
+				continue
+			}
+			scanResult.mu.Lock()
+			scanResult.add(&scanResult.noscriptTrackers, src, e.Request.URL.String())
+			scanResult.mu.Unlock()
+			scanResult.logger(e.Request.URL.String()).Info("TRACKER in <noscript>", "page", e.Request.URL.String(), "url", src)
 		}
 	})
 
-	c.OnHTML("iframe[src]", func(e *colly.HTMLElement) {
+	// the <source> elements of <picture>, <video> and <audio> and the media
+	// elements themselves load content just like <img>
+	c.OnHTML("source[src], source[srcset], video[src], audio[src], track[src]", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisMedia) {
+			return
+		}
 		scanResult.mu.Lock()
 		defer scanResult.mu.Unlock()
-		src := e.Attr("src")
+		for _, src := range append([]string{e.Attr("src")}, parseSrcset(e.Attr("srcset"))...) {
+			if src == "" || strings.HasPrefix(src, "data:") || strings.HasPrefix(src, "blob:") {
+				continue
+			}
+			src = resolveUrl(documentBase(e), src)
+			if isSameDomain(src, domain) {
+				continue
+			}
+			scanResult.add(&scanResult.media, src, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("3RD PARTY <"+e.Name+">", "page", e.Request.URL.String(), "url", src)
+		}
+	})
 
-		if src != "" {
-			thirdParty := !isSameDomain(src, baseUrl, domain)
-			if strings.Contains(src, "googletagmanager.com") {
-				scanResult.googleAnalyticsIFrame = true
-				if *verbose {
-					fmt.Printf("GOOGLE ANALYTICS <iframe> sourced on %s: %s\n", e.Request.URL, src)
-				}
-				return
+	// forms posting to 3rd parties send what users type off-site
+	c.OnHTML("form[action]", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisForms) {
+			return
+		}
+		action := resolveUrl(documentBase(e), strings.TrimSpace(e.Attr("action")))
+		if !strings.HasPrefix(action, "http") || isSameDomain(action, domain) {
+			return
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.addFormEndpoint(action, e.Attr("method"), e.Request.URL.String())
+	})
+
+	// style attributes can't declare fonts or imports, but load background
+	// and other images with url()
+	c.OnHTML("[style]", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisCss) || !strings.Contains(e.Attr("style"), "url(") {
+			return
+		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		for _, m := range cssUrlRegexp.FindAllStringSubmatch(e.Attr("style"), -1) {
+			src := strings.TrimSpace(m[1])
+			if src == "" || strings.HasPrefix(src, "data:") {
+				continue
 			}
-			if thirdParty {
-				if !slices.Contains(scanResult.otherIFrames, src) {
-					scanResult.otherIFrames = append(scanResult.otherIFrames, src)
-				}
-				if *verbose {
-					fmt.Printf("3RD PARTY <iframe> sourced on %s: %s\n", e.Request.URL, src)
-				}
-				return
+			src = resolveUrl(documentBase(e), src)
+			if isSameDomain(src, domain) {
+				continue
 			}
+			scanResult.add(&scanResult.inlineStyleUrls, src, e.Request.URL.String())
+			scanResult.logger(e.Request.URL.String()).Info("3RD PARTY url() in style attribute", "page", e.Request.URL.String(), "url", src)
 		}
 	})
 
 	c.OnHTML("style", func(e *colly.HTMLElement) {
+		if *listUrls || !analyzes(analysisFonts, analysisCss) {
+			return
+		}
+		// the imports are fetched once the lock is released, see fetchResource
+		var imports []string
+		defer func() {
+			for _, u := range imports {
+				fetchImport(c, u, e.Request, 0)
+			}
+		}()
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
 		if e.Text != "" {
 			if cssRegexp.MatchString(e.Text) {
 				result := cssRegexp.FindAllStringSubmatch(e.Text, -1)
 				for _, m := range result {
-					sm := m[2]
-					if strings.Contains(sm, "googleapis.com") {
-						if !slices.Contains(scanResult.googleFontsStyle, sm) {
-							scanResult.googleFontsStyle = append(scanResult.googleFontsStyle, sm)
+					sm := resolveUrl(documentBase(e), m[2])
+					scanResult.matchRules(ruleCssImport, sm, e.Request.URL.String())
+					if isGoogleFontsUrl(sm) {
+						if !analyzes(analysisFonts) {
+							continue
 						}
-						if *verbose {
-							fmt.Printf("STYLE / GOOGLEFONT @import in %s: %s\n", e.Request.URL, sm)
+						scanResult.add(&scanResult.googleFontsStyle, sm, e.Request.URL.String())
+						scanResult.logger(e.Request.URL.String()).Info("STYLE / GOOGLEFONT @import", "page", e.Request.URL.String(), "url", sm)
+						continue
+					}
+					if isFontServiceUrl(sm) {
+						if !analyzes(analysisFonts) {
+							continue
 						}
+						scanResult.add(&scanResult.remoteFonts, sm, e.Request.URL.String())
+						scanResult.logger(e.Request.URL.String()).Info("STYLE / FONT @import", "page", e.Request.URL.String(), "url", sm)
 						continue
 					}
-					thirdParty := !isSameDomain(sm, baseUrl, domain)
+					thirdParty := !isSameDomain(sm, domain)
 					if thirdParty {
-						if !slices.Contains(scanResult.otherStyle, sm) {
-							scanResult.otherStyle = append(scanResult.otherStyle, sm)
-						}
-						if *verbose {
-							fmt.Printf("3RD PARTY @import in <style> %s: %s\n", e.Request.URL, sm)
+						if !analyzes(analysisCss) {
+							continue
 						}
+						scanResult.add(&scanResult.otherStyle, sm, e.Request.URL.String())
+						scanResult.logger(e.Request.URL.String()).Info("3RD PARTY @import in <style>", "page", e.Request.URL.String(), "url", sm)
 						continue
 					}
+					imports = append(imports, sm)
 				}
 			}
+			for _, font := range findFontFaceUrls(e.Text, documentBase(e)) {
+				if !isSameDomain(font, domain) && analyzes(analysisFonts) {
+					scanResult.add(&scanResult.remoteFonts, font, e.Request.URL.String())
+					scanResult.logger(e.Request.URL.String()).Info("STYLE / FONT @font-face", "page", e.Request.URL.String(), "url", font)
+				}
+			}
+		}
+	})
+
+	// with -ua-list the findings of a response are attributed to its agent,
+	// this comes before the other callbacks which record them
+	if len(userAgents) > 0 {
+		c.OnResponse(func(r *colly.Response) {
+			scanResult.agents.Store(r.Request.URL.String(), agentOf(r.Request))
+		})
+	}
+
+	c.OnResponse(func(r *colly.Response) {
+		contentType := r.Headers.Get("Content-Type")
+		if *saveDir == "" || !isTextContent(contentType) {
+			return
+		}
+		if err := saveBody(*saveDir, r.Request.URL, contentType, r.Body); err != nil {
+			slog.Warn("error saving response", "url", r.Request.URL.String(), "error", err)
+			return
 		}
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.savedFiles += 1
+		slog.Debug("SAVED", "file", savePath(*saveDir, r.Request.URL, contentType))
 	})
 
 	c.OnResponse(func(r *colly.Response) {
-		if strings.HasSuffix(r.Request.URL.Path, "css") {
+		// the imports are fetched once the lock is released, see fetchResource
+		var imports []string
+		defer func() {
+			level, _ := r.Ctx.GetAny("importLevel").(int)
+			for _, u := range imports {
+				fetchImport(c, u, r.Request, level)
+			}
+		}()
+		scanResult.mu.Lock()
+		defer scanResult.mu.Unlock()
+		scanResult.reachable = true
+		scanResult.addStatus(r.StatusCode, r.Request.URL, resourceKind(r.Request) == "", domain)
+		scanResult.totalBytes += int64(len(r.Body))
+		if isCss(r) {
+			scanResult.cssBytes += int64(len(r.Body))
+		} else if strings.Contains(r.Headers.Get("Content-Type"), "html") {
+			scanResult.htmlBytes += int64(len(r.Body))
+		}
+		truncated := isTruncated(r)
+		if truncated {
+			slog.Debug("TRUNCATED, body larger than -max-body", "url", r.Request.URL.String(), "limit", formatBytes(int64(*maxBody)))
+		}
+		if *listUrls {
+			page := normalizeUrl(r.Request.URL.String())
+			if strings.Contains(r.Headers.Get("Content-Type"), "html") && !slices.Contains(scanResult.pages, page) {
+				scanResult.pages = append(scanResult.pages, page)
+			}
+			return
+		}
+		scanResult.addCookies(r, domain)
+		chain := scanResult.followRedirects(seedUrl)
+		if normalizeUrl(r.Request.URL.String()) == normalizeUrl(chain[len(chain)-1]) {
+			scanResult.addCspOrigins(*r.Headers, domain)
+		}
+
+		if resourceKind(r.Request) == "script" {
+			if truncated {
+				slog.Debug("SKIPPED script analysis of truncated body", "url", r.Request.URL.String())
+				return
+			}
+			scanResult.addScriptEndpoints(string(r.Body), "script", domain, r.Request.URL, r.Request.URL.String())
+			scanResult.addWorkers(string(r.Body), "script", domain, r.Request.URL, r.Request.URL.String())
+			return
+		}
+		if isCss(r) && truncated {
+			slog.Debug("SKIPPED css analysis of truncated body", "url", r.Request.URL.String())
+			return
+		}
+		if isCss(r) {
 
 			body := string(r.Body)
 			if cssRegexp.MatchString(body) {
 				result := cssRegexp.FindAllStringSubmatch(body, -1)
 				for _, m := range result {
-					sm := m[2]
-					if strings.Contains(sm, "googleapis.com") {
-						if !slices.Contains(scanResult.googleFontsCss, sm) {
-							scanResult.googleFontsCss = append(scanResult.googleFontsCss, sm)
+					sm := resolveUrl(r.Request.URL, m[2])
+					scanResult.matchRules(ruleCssImport, sm, r.Request.URL.String())
+					if isGoogleFontsUrl(sm) {
+						if !analyzes(analysisFonts) {
+							continue
 						}
-						if *verbose {
-							fmt.Printf("CSS / GOOGLEFONT @import in %s: %s\n", urlString+r.Request.URL.Path, sm)
+						scanResult.add(&scanResult.googleFontsCss, sm, r.Request.URL.String())
+						scanResult.logger(r.Request.URL.String()).Info("CSS / GOOGLEFONT @import", "css", r.Request.URL.String(), "url", sm)
+						continue
+					}
+					if isFontServiceUrl(sm) {
+						if !analyzes(analysisFonts) {
+							continue
 						}
+						scanResult.add(&scanResult.remoteFonts, sm, r.Request.URL.String())
+						scanResult.logger(r.Request.URL.String()).Info("CSS / FONT @import", "css", r.Request.URL.String(), "url", sm)
 						continue
 					}
-					thirdParty := !isSameDomain(sm, baseUrl, domain)
+					thirdParty := !isSameDomain(sm, domain)
 					if thirdParty {
-						if !slices.Contains(scanResult.otherCss, sm) {
-							scanResult.otherCss = append(scanResult.otherCss, sm)
-						}
-						if *verbose {
-							fmt.Printf("3RD PARTY @import in css file %s: %s\n", urlString+r.Request.URL.Path, sm)
+						if !analyzes(analysisCss) {
+							continue
 						}
+						scanResult.add(&scanResult.otherCss, sm, r.Request.URL.String())
+						scanResult.logger(r.Request.URL.String()).Info("3RD PARTY @import in css file", "css", r.Request.URL.String(), "url", sm)
 						continue
 					}
+					imports = append(imports, sm)
+				}
+			}
+			for _, font := range findFontFaceUrls(body, r.Request.URL) {
+				if !isSameDomain(font, domain) && analyzes(analysisFonts) {
+					scanResult.add(&scanResult.remoteFonts, font, r.Request.URL.String())
+					scanResult.logger(r.Request.URL.String()).Info("CSS / FONT @font-face", "css", r.Request.URL.String(), "url", font)
 				}
 			}
 		}
 	})
 
-	c.Visit(urlString)
+	err = c.Visit(seedUrl)
+	if err == nil && *useSitemap && *depth != 0 && !local {
+		seedFromSitemap(ctx, c, transport, baseUrl, allowedHosts, extraHeaders)
+	}
+	if err == nil && *depth != 0 && !local {
+		seedPagination(c, allowedHosts)
+	}
 	c.Wait()
-	fmt.Println()
-	printResult(&scanResult)
+	seedTLS.mu.Lock()
+	scanResult.tls = seedTLS.info
+	seedTLS.mu.Unlock()
+	if *checkExternal && !local {
+		scanResult.externalChecks = checkExternalLinks(ctx, transport, scanResult.externalLinks)
+	}
+	scanResult.redirectChain = scanResult.followRedirects(seedUrl)
+	// links of a website the seed url redirected away from are not followed
+	if final, err := url.Parse(scanResult.redirectChain[len(scanResult.redirectChain)-1]); err == nil && !slices.Contains(allowedHosts, final.Hostname()) {
+		fmt.Fprintf(os.Stderr, "warning: %s redirects to %s, links on host %s are not crawled, add -crawl-domain %s to crawl it\n",
+			seedUrl, final, final.Hostname(), final.Hostname())
+	}
+	if !*discoveryOrder {
+		scanResult.sortResults()
+	}
+	if showProgress() {
+		printProgress(&scanResult, true)
+		fmt.Fprintln(os.Stderr)
+	}
+	hosts := maps.Keys(scanResult.hostVisits)
+	slices.Sort(hosts)
+	for _, host := range hosts {
+		slog.Debug("HOST VISITS", "host", host, "visits", scanResult.hostVisits[host])
+	}
+	if ctx.Err() != nil {
+		fmt.Fprintf(os.Stderr, "stopped crawling %s: %v\n", urlString, ctx.Err())
+	}
+	if err != nil {
+		// robots.txt is requested before the seed page, so its certificate
+		// may fail before any request of the crawl
+		if issue := certificateIssue(err); issue != "" {
+			scanResult.addTLSIssue(u.Hostname(), issue)
+			return &scanResult, fmt.Errorf("error visiting %s: %w, -insecure accepts the certificate", urlString, err)
+		}
+		return &scanResult, fmt.Errorf("error visiting %s: %w", urlString, err)
+	}
+	if !scanResult.reachable {
+		return &scanResult, errors.New(urlString + " is not reachable")
+	}
+	return &scanResult, nil
+}
+
+// the flags of the global options are defined in init, so they have their
+// defaults in tests as well
+func init() {
+	depth = flag.Int("d", 3, "max depth for page visits when following links, 0 scans the given page only; stylesheets and their @imports are fetched regardless of it, see -import-depth")
+	verbose = flag.Bool("v", false, "verbose output, logs the findings and visited pages to stderr")
+	userAgent = flag.String("ua", "threepwoods-colly/"+version, "User-Agent header sent with each request")
+	robotsUserAgent = flag.String("robots-ua", "", "agent token robots.txt rules are evaluated for, like Googlebot, default the User-Agent of -ua")
+	delay = flag.Duration("delay", 0, "delay between requests, e.g. 200ms")
+	randomDelay = flag.Duration("random-delay", 200*time.Millisecond, "max random delay added to -delay")
+	parallelism = flag.Int("parallelism", 2, "max number of concurrent requests, across all websites with -concurrency")
+	concurrency = flag.Int("concurrency", 1, "number of websites scanned in parallel")
+	flag.Var(&headers, "header", "extra request header \"Name: Value\", can be repeated")
+	flag.Var(&cookieValues, "cookie", "cookie \"name=value\" sent to the website, can be repeated")
+	basicAuth = flag.String("basic-auth", "", "credentials for HTTP basic auth as user:pass")
+	insecure = flag.Bool("insecure", false, "accept invalid TLS certificates, like self-signed or expired ones of staging sites")
+	proxy = flag.String("proxy", "", "proxy url (http://, https:// or socks5://), defaults to HTTP_PROXY/HTTPS_PROXY")
+	useSitemap = flag.Bool("sitemap", false, "also visit all pages listed in /sitemap.xml")
+	retries = flag.Int("retries", 2, "number of retries with exponential backoff for failed requests")
+	timeout = flag.Duration("timeout", 0, "max duration of the crawl per website, e.g. 30s, 0 for no limit")
+	requestTimeout = flag.Duration("request-timeout", 15*time.Second, "max duration of a single request, slower requests fail, 0 for no limit")
+	maxPages = flag.Int("max", 0, "max number of pages to visit, 0 for no limit")
+	maxPagesPerHost = flag.Int("depth-per-host", 0, "max number of pages to visit per host, 0 for no limit")
+	maxBody = flag.Int("max-body", 10*1024*1024, "max size of a response body in bytes, larger bodies are cut off, 0 for no limit")
+	jsonOutput = flag.Bool("json", false, "print the result as json")
+	summary = flag.Bool("summary", false, "print a one line summary per website")
+	table = flag.Bool("table", false, "print the 3rd party resources as a table of type, count and a sample host")
+	timings = flag.Bool("timings", false, "report response times by resource type and host")
+	otelEndpoint = flag.String("otel-endpoint", "", "export a trace of each scan with a span per request to this OTLP/HTTP endpoint, like http://localhost:4318")
+	cacheDir = flag.String("cache-dir", "", "cache responses in this directory and reuse them in later scans")
+	noCache = flag.Bool("no-cache", false, "fetch all responses again, ignoring -cache-dir")
+	saveDir = flag.String("save-dir", "", "save the fetched html, css and other text responses below this directory")
+	discoveryOrder = flag.Bool("discovery-order", false, "list findings in the order they were found instead of sorting them")
+	stream = flag.Bool("stream", false, "print each finding as a json line as soon as it is found, and a summary to stderr at the end")
+	listUrls = flag.Bool("list-urls", false, "only print the urls of all pages the crawl would visit, without analyzing them")
+	localBaseUrl = flag.String("base-url", "", "url of the website a local file or stdin (-) belongs to, default "+defaultLocalBaseUrl)
+	importDepth = flag.Int("import-depth", 3, "max depth of @import chains of the website's stylesheets which are followed, 0 to not follow them")
+	scanScripts = flag.Bool("scan-scripts", false, "also fetch the scripts of the website to look for calls of 3rd party endpoints and workers")
+	checkExternal = flag.Bool("check-external", false, "request the first link to each external host once and report where it ends up")
+	ignoreQuery = flag.Bool("ignore-query", false, "ignore query strings when deciding whether a page was visited already")
+	includeSubdomains = flag.Bool("include-subdomains", false, "treat all subdomains of the website's registrable domain as 1st party")
+	flag.BoolVar(includeSubdomains, "same-site", false, "same as -include-subdomains")
+	flag.Var(&paginate, "paginate", "url template of pages which are visited besides the linked ones, like https://example.com/blog/page/{1..20}, can be repeated")
+	flag.Var(&crawlDomains, "crawl-domain", "other host of the website which is crawled as well and counts as 1st party, can be repeated")
+	ignoreRobots = flag.Bool("ignore-robots", false, "ignore restrictions set by robots.txt")
+	flag.Var(&allowedDomains, "allow-domain", "approved 3rd party domain including its subdomains, can be repeated")
 }
 
 func main() {
-	depth = flag.Int("d", 3, "max depth for page visits when following links")
-	verbose = flag.Bool("v", false, "verbose output")
+	noCrawl := flag.Bool("no-crawl", false, "scan the given page with its stylesheets and their @imports only, without following links, same as -d 0")
+	veryVerbose := flag.Bool("vv", false, "very verbose output, logs the details of the crawl as well")
+	logFormat := flag.String("log-format", "text", "format of the verbose logs: text or json")
+	userAgentFile := flag.String("ua-list", "", "file with one User-Agent per line, used in turn for the requests instead of -ua")
+	cookieFile := flag.String("cookie-file", "", "cookie jar file in the Netscape format, cookies of the website are sent with its requests")
+	only := flag.String("only", "", "only analyze these comma separated categories: analytics, fonts, scripts, iframes, css, links, images, media or forms")
+	metricsAddr := flag.String("metrics", "", "serve Prometheus metrics of the scans on /metrics of this address, like :9090, while the run lasts, pages are counted as they are loaded, the other counters once a website is done")
+	urlFile := flag.String("f", "", "file with one url per line to scan")
+	htmlFile := flag.String("html", "", "write a html report to this file")
+	csvFile := flag.String("csv", "", "write all 3rd party resources to this csv file")
+	diffFile := flag.String("diff", "", "compare with the results of a previous scan written by -json and print what changed")
+	sarifFile := flag.String("sarif", "", "write the findings to this file in the SARIF format of code scanning tools")
+	outputFile := flag.String("o", "", "write the report to this file instead of stdout")
+	noColor := flag.Bool("no-color", false, "disable colored output")
+	include := flag.String("include", "", "only visit pages with a path matching this regular expression")
+	exclude := flag.String("exclude", "", "skip pages with a path matching this regular expression")
+	printVersion := flag.Bool("version", false, "print the version and exit")
+	failOn := flag.String("fail-on", "none", "exit with a non-zero code on findings: ga, fonts, any-third-party, flagged or none")
+	allowlistFile := flag.String("allowlist", "", "file with one approved 3rd party domain per line")
+	rulesFile := flag.String("rules", "", "yaml or json file with additional detection rules")
+	configFile := flag.String("config", "", "yaml file with options, flags given on the command line take precedence")
 	flag.Parse()
+	if *configFile != "" {
+		config, err := readConfigFile(*configFile)
+		if err != nil {
+			log.Fatal("error reading config file: ", err)
+		}
+		if err := config.apply(); err != nil {
+			log.Fatal("error in config file: ", err)
+		}
+	}
+	// the stylesheets of a page are fetched regardless of the depth, so a
+	// depth of 0 analyzes the page with its @imports without crawling
+	if *noCrawl {
+		*depth = 0
+	}
+	if *printVersion {
+		fmt.Printf("threepwoods-colly %s (commit %s, built %s)\n", version, commit, date)
+		os.Exit(0)
+	}
+	if err := setupLogging(*verbose, *veryVerbose, *logFormat); err != nil {
+		log.Fatal(err)
+	}
+	*verbose = *verbose || *veryVerbose
+	var err error
+	if *include != "" {
+		if includePath, err = regexp.Compile(*include); err != nil {
+			log.Fatal("invalid -include expression: ", err)
+		}
+	}
+	if *exclude != "" {
+		if excludePath, err = regexp.Compile(*exclude); err != nil {
+			log.Fatal("invalid -exclude expression: ", err)
+		}
+	}
+	if onlyCategories, err = parseOnly(*only); err != nil {
+		log.Fatal(err)
+	}
+	if paginationUrls, err = parsePaginate(paginate); err != nil {
+		log.Fatal(err)
+	}
+	failOnLevel, ok := failOnLevels[*failOn]
+	if !ok {
+		log.Fatalf("invalid value %q for -fail-on, use ga, fonts, any-third-party, flagged or none", *failOn)
+	}
+	if *userAgentFile != "" {
+		if userAgents, err = readUrlFile(*userAgentFile); err != nil {
+			log.Fatal("error reading user agent list: ", err)
+		}
+	}
+	if *cookieFile != "" {
+		if fileCookies, err = readCookieFile(*cookieFile); err != nil {
+			log.Fatal("error reading cookie file: ", err)
+		}
+	}
+	if *allowlistFile != "" {
+		domains, err := readUrlFile(*allowlistFile)
+		if err != nil {
+			log.Fatal("error reading allowlist: ", err)
+		}
+		allowedDomains = append(allowedDomains, domains...)
+	}
+	if *rulesFile != "" {
+		if detectionRules, err = readRulesFile(*rulesFile); err != nil {
+			log.Fatal("error reading rules file: ", err)
+		}
+	}
+	if *metricsAddr != "" {
+		if err := serveMetrics(*metricsAddr); err != nil {
+			log.Fatal("error serving metrics: ", err)
+		}
+	}
+	shutdownTracing := func(context.Context) error { return nil }
+	if *otelEndpoint != "" {
+		if shutdownTracing, err = setupTracing(*otelEndpoint); err != nil {
+			log.Fatal("error setting up tracing: ", err)
+		}
+	}
 	values := flag.Args()
+	if *urlFile != "" {
+		urls, err := readUrlFile(*urlFile)
+		if err != nil {
+			log.Fatal("error reading url file: ", err)
+		}
+		values = append(values, urls...)
+	}
 	if len(values) == 0 {
-		fmt.Println("Usage: threepwoods-colly [-d 3] [-v] http://website.com")
+		fmt.Println("Usage: threepwoods-colly [options] http://website.com ...")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
-	checkUrl(values[0])
+
+	if len(userAgents) > 0 {
+		slog.Info("USER-AGENT", "agents", len(userAgents), "file", *userAgentFile)
+	} else {
+		slog.Info("USER-AGENT", "value", *userAgent)
+	}
+	if *robotsUserAgent != "" {
+		slog.Debug("ROBOTS USER-AGENT", "value", *robotsUserAgent)
+	}
+	slog.Info("LIMITS", "parallelism", *parallelism, "delay", delay.String(), "randomDelay", randomDelay.String())
+	for _, header := range headers {
+		name, _, _ := strings.Cut(header, ":")
+		if strings.EqualFold(strings.TrimSpace(name), "Authorization") {
+			header = name + ": [redacted]"
+		}
+		slog.Debug("HEADER", "value", header)
+	}
+	for _, cookie := range cookieValues {
+		name, _, _ := strings.Cut(cookie, "=")
+		slog.Debug("COOKIE", "value", name+"=[redacted]")
+	}
+	if *cookieFile != "" {
+		slog.Debug("COOKIES", "cookies", len(fileCookies), "file", *cookieFile)
+	}
+	if *basicAuth != "" {
+		username, _, _ := strings.Cut(*basicAuth, ":")
+		slog.Debug("BASIC-AUTH", "value", username+":[redacted]")
+	}
+	if *proxy != "" {
+		slog.Debug("PROXY", "url", *proxy)
+	} else {
+		for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+			if value := os.Getenv(name); value != "" {
+				slog.Debug("PROXY", "url", value, "from", name)
+				break
+			}
+		}
+	}
+
+	var output io.Writer = os.Stdout
+	// see https://no-color.org
+	useColor := !*noColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	if *outputFile != "" {
+		file, err := os.Create(*outputFile)
+		if err != nil {
+			log.Fatal("error creating output file: ", err)
+		}
+		defer file.Close()
+		output = file
+		useColor = false
+	}
+	// findings are streamed to the report, which is only a summary on
+	// stderr with -stream
+	streamEncoder = json.NewEncoder(output)
+
+	var previousResults []jsonResult
+	if *diffFile != "" {
+		if previousResults, err = readJsonResults(*diffFile); err != nil {
+			log.Fatal("error reading previous results: ", err)
+		}
+		// json files instead of urls are compared without scanning
+		if slices.IndexFunc(values, func(v string) bool { return !strings.HasSuffix(v, ".json") }) < 0 {
+			newTrackers, err := diffFiles(output, previousResults, values, useColor)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if newTrackers {
+				os.Exit(exitNewTrackers)
+			}
+			return
+		}
+	}
+
+	var csvWriter *csv.Writer
+	if *csvFile != "" {
+		file, err := os.Create(*csvFile)
+		if err != nil {
+			log.Fatal("error creating csv file: ", err)
+		}
+		defer file.Close()
+		csvWriter = csv.NewWriter(file)
+		csvWriter.Write([]string{"scanned_url", "resource_type", "resource_url", "is_google_analytics", "is_google_fonts", "is_google_tag_manager", "found_on"})
+	}
+
+	// the first Ctrl-C stops the crawl and still prints what was found so
+	// far, stop restores the default handling so a second one exits at once
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-interrupted.Done()
+		stop()
+	}()
+
+	// websites scanned in parallel share the request budget of -parallelism
+	if *concurrency > 1 {
+		requestSlots = make(chan struct{}, *parallelism)
+	}
+	type siteResult struct {
+		scanResult *ScanResult
+		err        error
+	}
+	scan := func(urlString string) siteResult {
+		ctx := interrupted
+		cancel := func() {}
+		if *timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, *timeout)
+		}
+		defer cancel()
+		scanResult, err := checkUrl(ctx, urlString)
+		if scanResult != nil {
+			metrics.addScan(scanResult)
+		}
+		return siteResult{scanResult, err}
+	}
+	// with -concurrency a pool of workers scans the websites ahead, while
+	// the reports are still printed one after another in the given order
+	results := make([]chan siteResult, len(values))
+	for i := range results {
+		results[i] = make(chan siteResult, 1)
+	}
+	if *concurrency > 1 {
+		jobs := make(chan int)
+		for w := 0; w < *concurrency; w++ {
+			go func() {
+				for i := range jobs {
+					results[i] <- scan(values[i])
+				}
+			}()
+		}
+		go func() {
+			defer close(jobs)
+			for i := range values {
+				select {
+				case jobs <- i:
+				case <-interrupted.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var reportResults []*ScanResult
+	failed := false
+	worst := severityNone
+	flagged := false
+	newTrackers := false
+	for i, urlString := range values {
+		details := !*jsonOutput && !*summary && !*listUrls && !*stream
+		if i > 0 && details {
+			fmt.Fprintln(output)
+			fmt.Fprintln(output, strings.Repeat("=", 60))
+			fmt.Fprintln(output)
+		}
+		if details {
+			fmt.Fprintln(output, "crawling", urlString)
+		}
+		if interrupted.Err() != nil {
+			break
+		}
+		var site siteResult
+		if *concurrency > 1 {
+			site = <-results[i]
+		} else {
+			site = scan(urlString)
+		}
+		scanResult, err := site.scanResult, site.err
+		if scanResult != nil {
+			// the report is written at once, so it isn't torn apart by the
+			// output of websites still being scanned
+			var report bytes.Buffer
+			if *listUrls {
+				slices.Sort(scanResult.pages)
+				for _, page := range scanResult.pages {
+					fmt.Fprintln(&report, page)
+				}
+			} else if *stream {
+				printSummary(os.Stderr, scanResult)
+			} else if *diffFile != "" {
+				diff := diffResults(findJsonResult(previousResults, scanResult.url), newJsonResult(scanResult))
+				printDiff(&report, diff, useColor)
+				newTrackers = newTrackers || len(diff.newTrackers) > 0
+			} else if *jsonOutput {
+				printJsonResult(&report, scanResult)
+			} else if *summary {
+				printSummary(&report, scanResult)
+			} else if *table {
+				printTable(&report, scanResult, useColor)
+			} else {
+				printResult(&report, scanResult, useColor)
+			}
+			output.Write(report.Bytes())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			failed = true
+		}
+		if scanResult != nil && scanResult.worstFinding() > worst {
+			worst = scanResult.worstFinding()
+		}
+		if scanResult != nil {
+			if _, f := scanResult.policyCheck(); len(f) > 0 {
+				flagged = true
+			}
+		}
+		if (*htmlFile != "" || *sarifFile != "") && scanResult != nil {
+			reportResults = append(reportResults, scanResult)
+		}
+		if csvWriter != nil && scanResult != nil {
+			if err := writeCsvRows(csvWriter, scanResult); err != nil {
+				log.Fatal("error writing csv file: ", err)
+			}
+		}
+	}
+	if *htmlFile != "" {
+		if err := writeHtmlReport(*htmlFile, reportResults); err != nil {
+			log.Fatal("error writing html report: ", err)
+		}
+	}
+	if *sarifFile != "" {
+		if err := writeSarifReport(*sarifFile, reportResults); err != nil {
+			log.Fatal("error writing sarif file: ", err)
+		}
+	}
+	// the spans which are left are sent before exiting, os.Exit skips
+	// deferred calls
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 10*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		slog.Warn("error exporting trace", "endpoint", *otelEndpoint, "error", err)
+	}
+	cancelFlush()
+	if interrupted.Err() != nil {
+		os.Exit(130)
+	}
+	if newTrackers {
+		os.Exit(exitNewTrackers)
+	}
+	if failOnLevel == severityFlagged {
+		if flagged {
+			os.Exit(int(severityFlagged))
+		}
+	} else if failOnLevel != severityNone && worst >= failOnLevel {
+		os.Exit(int(worst))
+	}
+	if failed {
+		os.Exit(1)
+	}
 }
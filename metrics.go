@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// scanMetrics are the counters of all scans of the run, served with -metrics
+// in the text format of Prometheus
+type scanMetrics struct {
+	mu                  sync.Mutex
+	pagesVisited        uint64
+	thirdPartyResources map[string]uint64
+	gaDetected          uint64
+	scans               uint64
+	scanSeconds         float64
+}
+
+// metrics counts the scans of the run
+var metrics = &scanMetrics{thirdPartyResources: map[string]uint64{}}
+
+// addPageVisit counts a page loaded completely by a scan
+func (m *scanMetrics) addPageVisit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pagesVisited += 1
+}
+
+// addScan counts a finished scan with its duration, its 3rd party resources
+// by type and whether it found Google Analytics
+func (m *scanMetrics) addScan(scanResult *ScanResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans += 1
+	m.scanSeconds += time.Since(scanResult.scannedAt).Seconds()
+	for _, list := range scanResult.resourceLists() {
+		m.thirdPartyResources[list.resourceType] += uint64(len(list.urls))
+	}
+	if scanResult.googleAnalyticsScriptSrc || scanResult.googleAnalyticsIFrame || scanResult.googleAnalyticsScript {
+		m.gaDetected += 1
+	}
+}
+
+// write writes the metrics in the Prometheus text format
+func (m *scanMetrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fmt.Fprintln(w, "# HELP pages_visited_total Pages loaded completely by all scans, failed and cut off pages are left out.")
+	fmt.Fprintln(w, "# TYPE pages_visited_total counter")
+	fmt.Fprintf(w, "pages_visited_total %d\n", m.pagesVisited)
+	fmt.Fprintln(w, "# HELP third_party_resources_total Distinct 3rd party resources found per website by type, counted once the website is done.")
+	fmt.Fprintln(w, "# TYPE third_party_resources_total counter")
+	types := maps.Keys(m.thirdPartyResources)
+	slices.Sort(types)
+	for _, t := range types {
+		fmt.Fprintf(w, "third_party_resources_total{type=%q} %d\n", t, m.thirdPartyResources[t])
+	}
+	fmt.Fprintln(w, "# HELP ga_detected_total Websites found using Google Analytics, counted once the website is done.")
+	fmt.Fprintln(w, "# TYPE ga_detected_total counter")
+	fmt.Fprintf(w, "ga_detected_total %d\n", m.gaDetected)
+	fmt.Fprintln(w, "# HELP scan_duration_seconds Duration of the finished scans.")
+	fmt.Fprintln(w, "# TYPE scan_duration_seconds summary")
+	fmt.Fprintf(w, "scan_duration_seconds_sum %g\n", m.scanSeconds)
+	fmt.Fprintf(w, "scan_duration_seconds_count %d\n", m.scans)
+}
+
+// serveMetrics serves the metrics on /metrics of the address in the
+// background, failing right away if it can't listen on it
+func serveMetrics(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.write(w)
+	})
+	go http.Serve(listener, mux)
+	return nil
+}
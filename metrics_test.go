@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPagesVisitedMetric checks only pages which were loaded completely are
+// counted as visited
+func TestPagesVisitedMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<a href="/ok.html">ok</a><a href="/missing.html">missing</a><a href="/slow.html">slow</a><a href="/large.html">large</a>`)
+		case "/ok.html":
+			fmt.Fprint(w, `<html></html>`)
+		case "/slow.html":
+			select {
+			case <-r.Context().Done():
+			case <-time.After(10 * time.Second):
+			}
+		case "/large.html":
+			fmt.Fprint(w, strings.Repeat("<p>large</p>", 1000))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	setOption(t, &metrics, &scanMetrics{thirdPartyResources: map[string]uint64{}})
+	setOption(t, maxBody, 1024)
+	setOption(t, requestTimeout, 200*time.Millisecond)
+	setOption(t, retries, 0)
+	scan(t, server.URL+"/")
+
+	if metrics.pagesVisited != 2 {
+		t.Errorf("counted %d pages as visited, want 2", metrics.pagesVisited)
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+)
+
+// mixedContent is a resource loaded over http by a https page, which
+// browsers block or warn about
+type mixedContent struct {
+	Url  string `json:"url"`
+	Page string `json:"page"`
+}
+
+// addMixedContent records a resource of a https page loaded over http.
+// Protocol relative urls are resolved to https and are fine.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addMixedContent(resource string, page *url.URL) {
+	if page.Scheme != "https" || !strings.HasPrefix(strings.ToLower(resource), "http://") {
+		return
+	}
+	finding := mixedContent{Url: resource, Page: page.String()}
+	for _, known := range scanResult.mixedContent {
+		if known == finding {
+			return
+		}
+	}
+	scanResult.mixedContent = append(scanResult.mixedContent, finding)
+	scanResult.emit("mixedContent", finding.Url, finding.Page, nil)
+	scanResult.logger(finding.Page).Info("MIXED CONTENT", "page", finding.Page, "url", resource)
+}
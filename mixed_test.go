@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestAddMixedContent(t *testing.T) {
+	tests := []struct {
+		page, ref string
+		mixed     bool
+	}{
+		{"https://example.com/", "http://cdn.other.com/app.js", true},
+		{"https://example.com/", "HTTP://cdn.other.com/style.css", true},
+		{"https://example.com/", "http://example.com/logo.png", true},
+		{"https://example.com/", "//cdn.other.com/app.js", false},
+		{"https://example.com/", "https://cdn.other.com/app.js", false},
+		{"https://example.com/", "/local.js", false},
+		{"https://example.com/", "data:image/png;base64,AAAA", false},
+		{"http://example.com/", "http://cdn.other.com/app.js", false},
+		{"http://example.com/", "//cdn.other.com/app.js", false},
+	}
+	for _, test := range tests {
+		page, err := url.Parse(test.page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var scanResult ScanResult
+		scanResult.addMixedContent(resolveUrl(page, test.ref), page)
+		scanResult.addMixedContent(resolveUrl(page, test.ref), page)
+		want := 0
+		if test.mixed {
+			want = 1
+		}
+		if len(scanResult.mixedContent) != want {
+			t.Errorf("%s on %s recorded as mixed content %d times, want %d", test.ref, test.page, len(scanResult.mixedContent), want)
+		}
+	}
+}
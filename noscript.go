@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+// noscriptElements parses the content of a <noscript> element, which is
+// only text when parsed with scripting enabled as browsers do, and returns
+// the elements matching the selector
+func noscriptElements(e *colly.HTMLElement, selector string) []*colly.HTMLElement {
+	if !strings.Contains(e.Text, "<") {
+		return nil
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(e.Text))
+	if err != nil {
+		return nil
+	}
+	var elements []*colly.HTMLElement
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		elements = append(elements, colly.NewHTMLElementFromSelectionNode(e.Response, s, s.Get(0), i))
+	})
+	return elements
+}
+
+// isNoscriptTracker reports whether an image or iframe of a <noscript>
+// element is the fallback of a tracker for browsers without JavaScript:
+// Google Analytics, Tag Manager, tracking pixels and images of analytics and
+// advertising services
+func isNoscriptTracker(e *colly.HTMLElement, src string) bool {
+	if isGoogleAnalyticsUrl(src) || isGoogleTagManagerUrl(src) {
+		return true
+	}
+	if e.Name != "img" {
+		return false
+	}
+	if isPixelSize(e.Attr("width"), e.Attr("height")) {
+		return true
+	}
+	category := serviceCategory(src)
+	return category == categoryAnalytics || category == categoryAdvertising
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestNoscriptTrackers checks the pixels and iframes trackers place in
+// <noscript> are found, while other fallback content is not reported
+func TestNoscriptTrackers(t *testing.T) {
+	server := serveFiles(t, map[string]string{
+		"/index.html": `<html><head>
+<script>fbq('init', '123');</script>
+<noscript><img height="1" width="1" style="display:none" src="https://www.facebook.com/tr?id=123&ev=PageView&noscript=1"></noscript>
+</head><body>
+<noscript><iframe src="https://www.googletagmanager.com/ns.html?id=GTM-ABC123" height="0" width="0"></iframe></noscript>
+<noscript><img src="https://images.other.com/photo.jpg" width="600" height="400"></noscript>
+</body></html>`,
+	})
+	scanResult := scan(t, server.URL+"/")
+
+	want := []string{"https://www.facebook.com/tr?id=123&ev=PageView&noscript=1", "https://www.googletagmanager.com/ns.html?id=GTM-ABC123"}
+	if fmt.Sprint(scanResult.noscriptTrackers) != fmt.Sprint(want) {
+		t.Errorf("trackers in <noscript> = %v, want %v", scanResult.noscriptTrackers, want)
+	}
+}
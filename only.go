@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Categories of findings which can be picked with -only
+const (
+	analysisAnalytics = "analytics"
+	analysisFonts     = "fonts"
+	analysisScripts   = "scripts"
+	analysisIFrames   = "iframes"
+	analysisCss       = "css"
+	analysisLinks     = "links"
+	analysisImages    = "images"
+	analysisMedia     = "media"
+	analysisForms     = "forms"
+)
+
+// analysisCategories are all categories of -only
+var analysisCategories = []string{
+	analysisAnalytics,
+	analysisFonts,
+	analysisScripts,
+	analysisIFrames,
+	analysisCss,
+	analysisLinks,
+	analysisImages,
+	analysisMedia,
+	analysisForms,
+}
+
+// onlyCategories are the categories given with -only, all are analyzed if
+// it is empty
+var onlyCategories []string
+
+// parseOnly parses the comma separated categories of -only
+func parseOnly(value string) ([]string, error) {
+	var categories []string
+	for _, category := range strings.Split(value, ",") {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		if !slices.Contains(analysisCategories, category) {
+			return nil, fmt.Errorf("invalid category %q for -only, use %s", category, strings.Join(analysisCategories, ", "))
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
+
+// analyzes reports whether findings of the category are looked for. Links
+// are still followed when nothing is analyzed.
+func analyzes(categories ...string) bool {
+	if len(onlyCategories) == 0 {
+		return true
+	}
+	for _, category := range categories {
+		if slices.Contains(onlyCategories, category) {
+			return true
+		}
+	}
+	return false
+}
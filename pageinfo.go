@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/exp/slices"
+)
+
+// pageInfo is the metadata of the meta tags of the seed page
+type pageInfo struct {
+	Url       string `json:"url"`
+	Charset   string `json:"charset"`
+	Viewport  string `json:"viewport"`
+	Generator string `json:"generator"`
+	Referrer  string `json:"referrer"`
+}
+
+// leakingReferrerPolicies send the full url of a page to 3rd parties, even
+// from https pages to http resources for unsafe-url
+var leakingReferrerPolicies = []string{"unsafe-url", "no-referrer-when-downgrade"}
+
+// addMeta records a meta tag of the page. The charset is given on its own
+// or in a Content-Type http-equiv.
+func (info *pageInfo) addMeta(e *colly.HTMLElement) {
+	if charset := e.Attr("charset"); charset != "" {
+		info.Charset = charset
+	}
+	if strings.EqualFold(e.Attr("http-equiv"), "content-type") {
+		if _, params, err := mime.ParseMediaType(e.Attr("content")); err == nil && params["charset"] != "" {
+			info.Charset = params["charset"]
+		}
+	}
+	switch strings.ToLower(e.Attr("name")) {
+	case "viewport":
+		info.Viewport = e.Attr("content")
+	case "generator":
+		info.Generator = e.Attr("content")
+	case "referrer":
+		info.Referrer = strings.ToLower(strings.TrimSpace(e.Attr("content")))
+	}
+}
+
+// leaksReferrer reports whether the referrer policy sends full urls to 3rd
+// parties
+func (info *pageInfo) leaksReferrer() bool {
+	return slices.Contains(leakingReferrerPolicies, info.Referrer)
+}
+
+// printPageInfo writes the metadata of the seed page, values which are not
+// set are left out
+func printPageInfo(w io.Writer, info pageInfo) {
+	if info.Url == "" {
+		return
+	}
+	fmt.Fprintln(w, "Page info of", info.Url+":")
+	for _, field := range []struct{ name, value string }{
+		{"charset", info.Charset},
+		{"viewport", info.Viewport},
+		{"generator", info.Generator},
+		{"referrer policy", info.Referrer},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(w, "  %s: %s\n", field.name, field.value)
+		}
+	}
+	if info.leaksReferrer() {
+		fmt.Fprintln(w, "  the referrer policy sends the full url of pages to 3rd parties")
+	}
+}
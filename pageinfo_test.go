@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestPageInfoOfSeed checks the page info is of the page the seed url
+// redirects to, even if pages of -paginate are parsed before it
+func TestPageInfoOfSeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/home.html", http.StatusMovedPermanently)
+		case "/home.html":
+			time.Sleep(100 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<meta charset="utf-8"><meta name="generator" content="Home"><meta name="referrer" content="no-referrer">`)
+		case "/page/1", "/page/2":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<meta charset="iso-8859-1"><meta name="generator" content="Archive"><meta name="viewport" content="width=device-width">`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	setOption(t, &paginationUrls, []string{server.URL + "/page/1", server.URL + "/page/2"})
+	scanResult := scan(t, server.URL+"/")
+
+	want := pageInfo{Url: server.URL + "/home.html", Charset: "utf-8", Generator: "Home", Referrer: "no-referrer"}
+	if scanResult.pageInfo != want {
+		t.Errorf("page info = %+v, want %+v", scanResult.pageInfo, want)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/exp/slices"
+)
+
+// maxPaginationUrls bounds the number of urls a -paginate template expands to
+const maxPaginationUrls = 10000
+
+// paginationRangeRegexp matches a numeric brace range like {1..20}
+var paginationRangeRegexp = regexp.MustCompile(`\{(\d+)\.\.(\d+)\}`)
+
+// paginationUrls are the urls expanded from the -paginate templates
+var paginationUrls []string
+
+// expandPagination expands the brace ranges of a -paginate template, like
+// https://example.com/blog/page/{1..20}, to all urls they stand for. Each
+// range multiplies the urls of the ones before it. Numbers are padded with
+// zeros like the start of the range, so {01..10} gives 01, 02 up to 10.
+func expandPagination(template string) ([]string, error) {
+	match := paginationRangeRegexp.FindStringSubmatchIndex(template)
+	if match == nil {
+		return []string{template}, nil
+	}
+	first, last := template[match[2]:match[3]], template[match[4]:match[5]]
+	start, err := strconv.Atoi(first)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range in -paginate template %q: %w", template, err)
+	}
+	end, err := strconv.Atoi(last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid range in -paginate template %q: %w", template, err)
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid range {%s..%s} in -paginate template %q, the end is before the start", first, last, template)
+	}
+	width := 0
+	if len(first) > 1 && first[0] == '0' {
+		width = len(first)
+	}
+	rest, err := expandPagination(template[match[1]:])
+	if err != nil {
+		return nil, err
+	}
+	var urls []string
+	for n := start; n <= end; n++ {
+		for _, r := range rest {
+			urls = append(urls, fmt.Sprintf("%s%0*d%s", template[:match[0]], width, n, r))
+			if len(urls) > maxPaginationUrls {
+				return nil, fmt.Errorf("-paginate template %q expands to more than %d urls", template, maxPaginationUrls)
+			}
+		}
+	}
+	return urls, nil
+}
+
+// parsePaginate expands all -paginate templates, each url has to be an
+// absolute http or https url
+func parsePaginate(templates []string) ([]string, error) {
+	var urls []string
+	for _, template := range templates {
+		expanded, err := expandPagination(template)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range expanded {
+			parsed, err := url.Parse(u)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+				return nil, fmt.Errorf("invalid -paginate url %q, expected an absolute http or https url", u)
+			}
+			urls = append(urls, u)
+		}
+	}
+	return urls, nil
+}
+
+// seedPagination visits the urls of -paginate which belong to the hosts
+// crawled for the website, the others are meant for other websites
+func seedPagination(c *colly.Collector, allowedHosts []string) {
+	for _, page := range paginationUrls {
+		u, err := url.Parse(page)
+		if err != nil || !slices.Contains(allowedHosts, u.Hostname()) {
+			slog.Debug("SKIPPED pagination url of another website", "url", page)
+			continue
+		}
+		c.Visit(normalizeUrl(page))
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// platform is a shop system, CMS or website builder, recognized by signatures
+// in the urls of the website's resources or by the meta generator tag. The
+// 3rd party resources from its hosts are loaded by the platform itself, not
+// added by the website.
+type platform struct {
+	name       string
+	signatures []string
+	generator  string
+	hosts      []string
+}
+
+// platforms are the known platforms, matched in order
+var platforms = []platform{
+	{
+		name:       "Shopify",
+		signatures: []string{"cdn.shopify.com", "/cdn/shop/"},
+		generator:  "shopify",
+		hosts:      []string{"shopify.com", "shopifycdn.com", "shopifysvc.com", "shopifycloud.com", "shop.app"},
+	},
+	{
+		name:       "WordPress",
+		signatures: []string{"/wp-content/", "/wp-includes/"},
+		generator:  "wordpress",
+		hosts:      []string{"s.w.org", "wp.com", "wordpress.com", "wordpress.org", "gravatar.com"},
+	},
+	{
+		name:       "Wix",
+		signatures: []string{"static.parastorage.com", "static.wixstatic.com"},
+		generator:  "wix.com",
+		hosts:      []string{"parastorage.com", "wixstatic.com", "wix.com", "wixapps.net"},
+	},
+	{
+		// the fonts of the templates are served by Adobe Fonts
+		name:       "Squarespace",
+		signatures: []string{"squarespace.com", "squarespace-cdn.com"},
+		generator:  "squarespace",
+		hosts:      []string{"squarespace.com", "squarespace-cdn.com", "use.typekit.net"},
+	},
+}
+
+// platformOfUrl returns the name of the platform a resource url belongs to,
+// or "" if it has no signature of a known platform
+func platformOfUrl(u string) string {
+	u = strings.ToLower(u)
+	for _, platform := range platforms {
+		for _, signature := range platform.signatures {
+			if strings.Contains(u, signature) {
+				return platform.name
+			}
+		}
+	}
+	return ""
+}
+
+// platformOfGenerator returns the name of the platform named by the content of
+// a meta generator tag, like "WordPress 6.4.2", or ""
+func platformOfGenerator(content string) string {
+	content = strings.ToLower(strings.TrimSpace(content))
+	for _, platform := range platforms {
+		if strings.HasPrefix(content, platform.generator) {
+			return platform.name
+		}
+	}
+	return ""
+}
+
+// setPlatform records the platform of the website, the first one detected
+// wins. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) setPlatform(name, evidence, page string) {
+	if name == "" || scanResult.platform != "" {
+		return
+	}
+	scanResult.platform = name
+	scanResult.logger(page).Info("PLATFORM", "platform", name, "evidence", evidence, "page", page)
+}
+
+// isPlatformResource reports whether a 3rd party resource is loaded from a
+// host of the website's platform
+func (scanResult *ScanResult) isPlatformResource(resource string) bool {
+	i := slices.IndexFunc(platforms, func(p platform) bool {
+		return p.name == scanResult.platform
+	})
+	if i < 0 {
+		return false
+	}
+	host := resourceHost(resource)
+	for _, h := range platforms[i].hosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// platformResources returns the 3rd party resources which are loaded by the
+// platform of the website, all others were added by the website
+func (scanResult *ScanResult) platformResources() []string {
+	var resources []string
+	for _, list := range scanResult.resourceLists() {
+		for _, resource := range list.urls {
+			if scanResult.isPlatformResource(resource) && !slices.Contains(resources, resource) {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	return resources
+}
+
+// platformNote marks a 3rd party resource loaded by the website's platform
+// in the report
+func (scanResult *ScanResult) platformNote(resource string) string {
+	if !scanResult.isPlatformResource(resource) {
+		return ""
+	}
+	return " (" + scanResult.platform + " default)"
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// maxRedirects is the number of redirects followed for a single request
+const maxRedirects = 10
+
+// redirect is one hop of a redirect chain
+type redirect struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	ThirdParty bool   `json:"thirdParty"`
+}
+
+// redirectTransport reports every redirect response it sees, including
+// redirects to other hosts which are not followed
+type redirectTransport struct {
+	base       http.RoundTripper
+	onRedirect func(from, to *url.URL, status int)
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		if location, err := resp.Location(); err == nil {
+			t.onRedirect(req.URL, location, resp.StatusCode)
+		}
+	}
+	return resp, nil
+}
+
+// checkRedirect caps the number of redirects and drops the Authorization
+// header when a redirect leaves the host, like colly does by default
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if req.URL.Host != via[len(via)-1].URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
+
+// addRedirect records a redirect unless it is known already.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addRedirect(from, to *url.URL, domain string) {
+	hop := redirect{From: from.String(), To: to.String(), ThirdParty: !isSameDomain(to.String(), domain)}
+	for _, known := range scanResult.redirects {
+		if known == hop {
+			return
+		}
+	}
+	scanResult.redirects = append(scanResult.redirects, hop)
+	if hop.ThirdParty {
+		scanResult.add(&scanResult.otherRedirects, hop.To, hop.From)
+	}
+}
+
+// redirectsToThirdParty reports whether the url redirected to a 3rd party
+// host. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) redirectsToThirdParty(u string) bool {
+	for _, hop := range scanResult.redirects {
+		if hop.From == u && hop.ThirdParty {
+			return true
+		}
+	}
+	return false
+}
+
+// followRedirects returns the urls the given url redirected to one after another
+func (scanResult *ScanResult) followRedirects(start string) []string {
+	chain := []string{start}
+	for len(chain) <= maxRedirects {
+		next := ""
+		for _, hop := range scanResult.redirects {
+			if normalizeUrl(hop.From) == normalizeUrl(chain[len(chain)-1]) {
+				next = hop.To
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		chain = append(chain, next)
+	}
+	return chain
+}
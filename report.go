@@ -0,0 +1,103 @@
+package main
+
+import (
+	_ "embed"
+	"html/template"
+	"os"
+	"time"
+)
+
+//go:embed report.html
+var reportTemplate string
+
+// reportRow is a single resource in a table of the html report
+type reportRow struct {
+	Type    string
+	Url     string
+	FoundOn []string
+}
+
+// reportSection is a table of the html report
+type reportSection struct {
+	Title string
+	Rows  []reportRow
+}
+
+// reportSite holds everything the html report shows for one website
+type reportSite struct {
+	Url           string
+	ScannedAt     time.Time
+	Visits        uint32
+	Severity      string
+	SeverityClass string
+	Sections      []reportSection
+}
+
+// severityLabels describe a severity with a text and a css class for the badge
+var severityLabels = map[severity]struct{ text, class string }{
+	severityNone:       {"no 3rd party resources", "ok"},
+	severityThirdParty: {"3rd party resources", "warning"},
+	severityFonts:      {"Google Fonts", "danger"},
+	severityAnalytics:  {"Google Analytics / Tag Manager", "danger"},
+}
+
+func newReportSite(scanResult *ScanResult) reportSite {
+	trackers := reportSection{Title: "Trackers"}
+	fonts := reportSection{Title: "Fonts"}
+	others := reportSection{Title: "3rd party resources"}
+	selfHosted := reportSection{Title: "Analytics (self-hosted)"}
+
+	for _, list := range scanResult.resourceLists() {
+		section := &others
+		switch {
+		case list.googleAnalytics || list.tagManager || list.resourceType == "tracking-pixel" || list.resourceType == "inline-reference":
+			section = &trackers
+		case list.googleFonts || list.resourceType == "font":
+			section = &fonts
+		}
+		for _, u := range list.urls {
+			section.Rows = append(section.Rows, reportRow{
+				Type:    list.resourceType,
+				Url:     u,
+				FoundOn: scanResult.foundOn[u],
+			})
+		}
+	}
+
+	for _, u := range scanResult.matomo {
+		selfHosted.Rows = append(selfHosted.Rows, reportRow{Type: "matomo", Url: u, FoundOn: scanResult.foundOn[u]})
+	}
+
+	label := severityLabels[scanResult.worstFinding()]
+	return reportSite{
+		Url:           scanResult.url,
+		ScannedAt:     scanResult.scannedAt,
+		Visits:        scanResult.visits.Load(),
+		Severity:      label.text,
+		SeverityClass: label.class,
+		Sections:      []reportSection{trackers, selfHosted, fonts, others},
+	}
+}
+
+// writeHtmlReport renders a self-contained html report of all scanned websites
+func writeHtmlReport(path string, scanResults []*ScanResult) error {
+	tmpl, err := template.New("report").Parse(reportTemplate)
+	if err != nil {
+		return err
+	}
+
+	var sites []reportSite
+	for _, scanResult := range scanResults {
+		sites = append(sites, newReportSite(scanResult))
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := tmpl.Execute(file, sites); err != nil {
+		return err
+	}
+	return file.Close()
+}
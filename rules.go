@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// Places in a page detection rules apply to
+const (
+	ruleScriptSrc = "script-src"
+	ruleInline    = "inline"
+	ruleIFrame    = "iframe"
+	ruleLink      = "link"
+	ruleCssImport = "css-import"
+)
+
+// ruleTargets are all places detection rules can apply to
+var ruleTargets = []string{ruleScriptSrc, ruleInline, ruleIFrame, ruleLink, ruleCssImport}
+
+// detectionRule is a detector of a rules file. Match is a substring of the
+// url or inline code, or a regular expression if enclosed in slashes.
+type detectionRule struct {
+	Name      string   `yaml:"name"`
+	Category  string   `yaml:"category"`
+	Match     string   `yaml:"match"`
+	AppliesTo []string `yaml:"appliesTo"`
+	regexp    *regexp.Regexp
+}
+
+// detectionRules are the rules loaded with -rules
+var detectionRules []detectionRule
+
+// ruleMatch is a detection rule which matched, with the resource it matched
+// and the pages it was found on. Resource is empty for inline code.
+type ruleMatch struct {
+	Rule      string   `json:"rule"`
+	Category  string   `json:"category"`
+	AppliesTo string   `json:"appliesTo"`
+	Resource  string   `json:"resource"`
+	Pages     []string `json:"pages"`
+}
+
+// readRulesFile decodes a yaml or json file with a list of detection rules,
+// rejecting unknown keys and invalid rules
+func readRulesFile(path string) ([]detectionRule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []detectionRule
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rules); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+	}
+	return rules, nil
+}
+
+// compile validates the rule and compiles its regular expression
+func (rule *detectionRule) compile() error {
+	if rule.Name == "" {
+		return errors.New("missing name")
+	}
+	if rule.Category == "" {
+		return fmt.Errorf("%s: missing category", rule.Name)
+	}
+	if rule.Match == "" {
+		return fmt.Errorf("%s: missing match", rule.Name)
+	}
+	if len(rule.AppliesTo) == 0 {
+		return fmt.Errorf("%s: missing appliesTo, use %s", rule.Name, strings.Join(ruleTargets, ", "))
+	}
+	for _, target := range rule.AppliesTo {
+		if !slices.Contains(ruleTargets, target) {
+			return fmt.Errorf("%s: invalid appliesTo %q, use %s", rule.Name, target, strings.Join(ruleTargets, ", "))
+		}
+	}
+	if len(rule.Match) > 1 && strings.HasPrefix(rule.Match, "/") && strings.HasSuffix(rule.Match, "/") {
+		re, err := regexp.Compile(rule.Match[1 : len(rule.Match)-1])
+		if err != nil {
+			return fmt.Errorf("%s: invalid match: %w", rule.Name, err)
+		}
+		rule.regexp = re
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to the target and matches the
+// url or code
+func (rule *detectionRule) matches(target, s string) bool {
+	if !slices.Contains(rule.AppliesTo, target) {
+		return false
+	}
+	if rule.regexp != nil {
+		return rule.regexp.MatchString(s)
+	}
+	return strings.Contains(s, rule.Match)
+}
+
+// matchRules records the detection rules matching a url or inline code found
+// on a page. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) matchRules(target, s, page string) {
+	if s == "" {
+		return
+	}
+	resource := s
+	if target == ruleInline {
+		resource = ""
+	}
+	for _, rule := range detectionRules {
+		if !rule.matches(target, s) {
+			continue
+		}
+		i := slices.IndexFunc(scanResult.matchedRules, func(m ruleMatch) bool {
+			return m.Rule == rule.Name && m.AppliesTo == target && m.Resource == resource
+		})
+		if i < 0 {
+			scanResult.matchedRules = append(scanResult.matchedRules, ruleMatch{Rule: rule.Name, Category: rule.Category, AppliesTo: target, Resource: resource})
+			i = len(scanResult.matchedRules) - 1
+			scanResult.logger(page).Info("RULE", "rule", rule.Name, "category", rule.Category, "appliesTo", target, "page", page, "url", resource)
+		}
+		if !slices.Contains(scanResult.matchedRules[i].Pages, page) {
+			scanResult.matchedRules[i].Pages = append(scanResult.matchedRules[i].Pages, page)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// sarifSchema is the json schema of the SARIF 2.1.0 format written by -sarif
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+// sarifLog and the types below are the subset of SARIF 2.1.0 written by
+// -sarif
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationUri string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	Id                   string             `json:"id"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+// sarifRuleOf returns the rule a list of 3rd party resources is reported
+// under, Google services have rules of their own
+func sarifRuleOf(list resourceList) sarifRule {
+	switch {
+	case list.googleAnalytics:
+		return sarifRule{"google-analytics", sarifMessage{"Website uses Google Analytics"}, sarifConfiguration{"error"}}
+	case list.tagManager:
+		return sarifRule{"google-tag-manager", sarifMessage{"Website uses Google Tag Manager"}, sarifConfiguration{"error"}}
+	case list.googleFonts:
+		return sarifRule{"google-fonts", sarifMessage{"Website loads Google Fonts"}, sarifConfiguration{"error"}}
+	}
+	description := fmt.Sprintf("Website loads a 3rd party resource (%s)", list.resourceType)
+	return sarifRule{"third-party-" + list.resourceType, sarifMessage{description}, sarifConfiguration{"warning"}}
+}
+
+// sarifRules describes every rule a finding can be reported under
+func sarifRules() []sarifRule {
+	var rules []sarifRule
+	seen := map[string]bool{}
+	for _, list := range (&ScanResult{}).resourceLists() {
+		rule := sarifRuleOf(list)
+		if !seen[rule.Id] {
+			seen[rule.Id] = true
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// sarifResults maps each 3rd party resource of a website to a result located
+// at the pages it was found on
+func sarifResults(scanResult *ScanResult) []sarifResult {
+	var results []sarifResult
+	for _, list := range scanResult.resourceLists() {
+		rule := sarifRuleOf(list)
+		for _, u := range list.urls {
+			result := sarifResult{
+				RuleId:  rule.Id,
+				Level:   rule.DefaultConfiguration.Level,
+				Message: sarifMessage{fmt.Sprintf("%s: %s", rule.ShortDescription.Text, u)},
+			}
+			pages := scanResult.foundOn[u]
+			if len(pages) == 0 {
+				pages = []string{scanResult.url}
+			}
+			for _, page := range pages {
+				result.Locations = append(result.Locations, sarifLocation{
+					sarifPhysicalLocation{sarifArtifactLocation{page}},
+				})
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// writeSarifReport writes the 3rd party resources of all scanned websites as
+// a single SARIF 2.1.0 run for code scanning tools
+func writeSarifReport(path string, scanResults []*ScanResult) error {
+	results := []sarifResult{}
+	for _, scanResult := range scanResults {
+		results = append(results, sarifResults(scanResult)...)
+	}
+	report := sarifLog{
+		Version: "2.1.0",
+		Schema:  sarifSchema,
+		Runs: []sarifRun{{
+			Tool: sarifTool{sarifDriver{
+				Name:           "threepwoods-colly",
+				Version:        version,
+				InformationUri: "https://github.com/chaosbiber/threepwoods-colly",
+				Rules:          sarifRules(),
+			}},
+			Results: results,
+		}},
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	return file.Close()
+}
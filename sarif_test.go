@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// TestSarifReport checks the report has the properties SARIF 2.1.0 requires
+// and every result refers to a described rule and the page it was found on
+func TestSarifReport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<script src="https://www.google-analytics.com/analytics.js"></script>
+<script src="https://cdn.other.com/widget.js"></script>`)
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "out.sarif")
+	if err := writeSarifReport(path, []*ScanResult{scan(t, server.URL+"/")}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report struct {
+		Version string `json:"version"`
+		Schema  string `json:"$schema"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						Id               string `json:"id"`
+						ShortDescription struct {
+							Text string `json:"text"`
+						} `json:"shortDescription"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleId  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							Uri string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Version != "2.1.0" || report.Schema != sarifSchema {
+		t.Errorf("version %q with $schema %q, want 2.1.0", report.Version, report.Schema)
+	}
+	if len(report.Runs) != 1 {
+		t.Fatalf("%d runs, want 1", len(report.Runs))
+	}
+	run := report.Runs[0]
+	if run.Tool.Driver.Name == "" {
+		t.Error("tool.driver.name is empty")
+	}
+	var rules []string
+	for _, rule := range run.Tool.Driver.Rules {
+		if slices.Contains(rules, rule.Id) || rule.ShortDescription.Text == "" {
+			t.Errorf("rule %q is described twice or without text", rule.Id)
+		}
+		rules = append(rules, rule.Id)
+	}
+	var found []string
+	for _, result := range run.Results {
+		found = append(found, result.RuleId)
+		if !slices.Contains(rules, result.RuleId) {
+			t.Errorf("result of undescribed rule %q", result.RuleId)
+		}
+		if !slices.Contains([]string{"none", "note", "warning", "error"}, result.Level) || result.Message.Text == "" {
+			t.Errorf("result %q has level %q and message %q", result.RuleId, result.Level, result.Message.Text)
+		}
+		for _, location := range result.Locations {
+			if u, err := url.Parse(location.PhysicalLocation.ArtifactLocation.Uri); err != nil || !u.IsAbs() {
+				t.Errorf("result %q located at no absolute uri %q", result.RuleId, location.PhysicalLocation.ArtifactLocation.Uri)
+			}
+		}
+		if len(result.Locations) == 0 {
+			t.Errorf("result %q has no location", result.RuleId)
+		}
+	}
+	slices.Sort(found)
+	if want := []string{"google-analytics", "third-party-script"}; fmt.Sprint(found) != fmt.Sprint(want) {
+		t.Errorf("results of rules %v, want %v", found, want)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// unsafeFileChars are characters replaced in file names of saved responses
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// isTextContent reports whether a response of the content type is worth
+// saving, binary content like images and fonts is skipped
+func isTextContent(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "javascript") ||
+		strings.HasSuffix(mediaType, "json") ||
+		strings.HasSuffix(mediaType, "xml")
+}
+
+// savePath maps the url of a response to a file below dir, mirroring the
+// host and path of the url. Pages without a file name are saved as
+// index.html in a directory of their path.
+func savePath(dir string, u *url.URL, contentType string) string {
+	segments := []string{safeFileName(u.Host)}
+	for _, segment := range strings.Split(strings.Trim(path.Clean("/"+u.Path), "/"), "/") {
+		if segment != "" {
+			segments = append(segments, safeFileName(segment))
+		}
+	}
+	name := segments[len(segments)-1]
+	if len(segments) == 1 || (strings.Contains(contentType, "html") && !strings.HasSuffix(name, ".html") && !strings.HasSuffix(name, ".htm")) {
+		segments = append(segments, "index.html")
+	}
+	if u.RawQuery != "" {
+		last := segments[len(segments)-1]
+		ext := path.Ext(last)
+		segments[len(segments)-1] = strings.TrimSuffix(last, ext) + "_" + safeFileName(u.RawQuery) + ext
+	}
+	return filepath.Join(append([]string{dir}, segments...)...)
+}
+
+// safeFileName replaces characters which are not safe in file names and
+// makes sure the name can't point to a parent directory
+func safeFileName(name string) string {
+	name = unsafeFileChars.ReplaceAllString(name, "_")
+	if name == "." || name == ".." {
+		return "_"
+	}
+	return name
+}
+
+// saveBody writes a response body to its file below dir
+func saveBody(dir string, u *url.URL, contentType string, body []byte) error {
+	file := savePath(dir, u, contentType)
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, body, 0o644)
+}
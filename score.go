@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// scoreWeights are the penalties subtracted from 100 for the findings of a
+// website, they can be changed with score-weights in the -config file
+var scoreWeights = map[string]int{
+	"google-analytics": 40,
+	"tag-manager":      30,
+	"tracking-pixels":  20,
+	"google-fonts":     20,
+	"missing-consent":  15,
+	"mixed-content":    10,
+	"third-party-host": 2,
+}
+
+// setScoreWeights overrides the default weights with the ones of the config
+// file, rejecting unknown findings and negative weights
+func setScoreWeights(weights map[string]int) error {
+	for name, weight := range weights {
+		if _, ok := scoreWeights[name]; !ok {
+			names := maps.Keys(scoreWeights)
+			slices.Sort(names)
+			return fmt.Errorf("unknown finding %q in score-weights, use %s", name, strings.Join(names, ", "))
+		}
+		if weight < 0 {
+			return fmt.Errorf("negative weight %d for %s in score-weights", weight, name)
+		}
+		scoreWeights[name] = weight
+	}
+	return nil
+}
+
+// score rates the privacy of a website from 0 to 100 by subtracting the
+// weights of its findings, each 3rd party host costs the weight once
+func (scanResult *ScanResult) score() int {
+	penalty := 0
+	if scanResult.googleAnalyticsScriptSrc || scanResult.googleAnalyticsIFrame || scanResult.googleAnalyticsScript {
+		penalty += scoreWeights["google-analytics"]
+	}
+	if scanResult.googleTagManagerScriptSrc || scanResult.googleTagManagerIFrame || scanResult.googleTagManagerScript {
+		penalty += scoreWeights["tag-manager"]
+	}
+	if len(scanResult.trackingPixels) > 0 {
+		penalty += scoreWeights["tracking-pixels"]
+	}
+	if scanResult.googleFontsLink || len(scanResult.googleFontsCss) > 0 || len(scanResult.googleFontsStyle) > 0 || scanResult.googleFontsScript {
+		penalty += scoreWeights["google-fonts"]
+	}
+	if scanResult.missingConsent() {
+		penalty += scoreWeights["missing-consent"]
+	}
+	if len(scanResult.mixedContent) > 0 {
+		penalty += scoreWeights["mixed-content"]
+	}
+	penalty += scanResult.Stats().ThirdPartyHosts * scoreWeights["third-party-host"]
+	return max(0, 100-penalty)
+}
+
+// grade maps a score to a letter from A to F
+func grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	case score >= 50:
+		return "E"
+	}
+	return "F"
+}
+
+// printScore writes the score and grade of a website
+func printScore(w io.Writer, score int) {
+	fmt.Fprintf(w, "Privacy score: %d/100, grade %s\n", score, grade(score))
+}
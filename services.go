@@ -0,0 +1,211 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// Categories of known third party services
+const (
+	categoryAnalytics   = "analytics"
+	categoryAdvertising = "advertising"
+	categorySocial      = "social"
+	categoryTagManager  = "tag-manager"
+	categorySupportChat = "support-chat"
+	categoryCDN         = "cdn"
+	categoryOther       = "other"
+)
+
+// serviceCategories is the order in which categories are printed
+var serviceCategories = []string{
+	categoryAnalytics,
+	categoryAdvertising,
+	categorySocial,
+	categoryTagManager,
+	categorySupportChat,
+	categoryCDN,
+	categoryOther,
+}
+
+// knownServices maps hosts of third party services to their category.
+// Subdomains of a listed host belong to the same category.
+var knownServices = map[string]string{
+	// analytics
+	"google-analytics.com":   categoryAnalytics,
+	"hotjar.com":             categoryAnalytics,
+	"mixpanel.com":           categoryAnalytics,
+	"segment.com":            categoryAnalytics,
+	"segment.io":             categoryAnalytics,
+	"amplitude.com":          categoryAnalytics,
+	"heap.io":                categoryAnalytics,
+	"heapanalytics.com":      categoryAnalytics,
+	"fullstory.com":          categoryAnalytics,
+	"clarity.ms":             categoryAnalytics,
+	"matomo.cloud":           categoryAnalytics,
+	"plausible.io":           categoryAnalytics,
+	"newrelic.com":           categoryAnalytics,
+	"nr-data.net":            categoryAnalytics,
+	"mouseflow.com":          categoryAnalytics,
+	"luckyorange.com":        categoryAnalytics,
+	"statcounter.com":        categoryAnalytics,
+	"mc.yandex.ru":           categoryAnalytics,
+	"cloudflareinsights.com": categoryAnalytics,
+
+	// advertising
+	"doubleclick.net":       categoryAdvertising,
+	"googlesyndication.com": categoryAdvertising,
+	"googleadservices.com":  categoryAdvertising,
+	"adservice.google.com":  categoryAdvertising,
+	"amazon-adsystem.com":   categoryAdvertising,
+	"adnxs.com":             categoryAdvertising,
+	"criteo.com":            categoryAdvertising,
+	"criteo.net":            categoryAdvertising,
+	"taboola.com":           categoryAdvertising,
+	"outbrain.com":          categoryAdvertising,
+	"bat.bing.com":          categoryAdvertising,
+	"ads-twitter.com":       categoryAdvertising,
+	"snap.licdn.com":        categoryAdvertising,
+	"adsrvr.org":            categoryAdvertising,
+	"pubmatic.com":          categoryAdvertising,
+	"rubiconproject.com":    categoryAdvertising,
+
+	// social
+	"connect.facebook.net":  categorySocial,
+	"facebook.com":          categorySocial,
+	"platform.twitter.com":  categorySocial,
+	"twitter.com":           categorySocial,
+	"platform.linkedin.com": categorySocial,
+	"linkedin.com":          categorySocial,
+	"instagram.com":         categorySocial,
+	"pinterest.com":         categorySocial,
+	"pinimg.com":            categorySocial,
+	"tiktok.com":            categorySocial,
+	"addthis.com":           categorySocial,
+	"sharethis.com":         categorySocial,
+	"disqus.com":            categorySocial,
+	"youtube.com":           categorySocial,
+	"youtube-nocookie.com":  categorySocial,
+	"vimeo.com":             categorySocial,
+
+	// tag managers
+	"googletagmanager.com": categoryTagManager,
+	"tagcommander.com":     categoryTagManager,
+	"tealiumiq.com":        categoryTagManager,
+	"ensighten.com":        categoryTagManager,
+	"assets.adobedtm.com":  categoryTagManager,
+
+	// support and chat widgets
+	"intercom.io":     categorySupportChat,
+	"intercomcdn.com": categorySupportChat,
+	"zendesk.com":     categorySupportChat,
+	"zdassets.com":    categorySupportChat,
+	"drift.com":       categorySupportChat,
+	"driftt.com":      categorySupportChat,
+	"crisp.chat":      categorySupportChat,
+	"tawk.to":         categorySupportChat,
+	"livechatinc.com": categorySupportChat,
+	"hubspot.com":     categorySupportChat,
+	"hs-scripts.com":  categorySupportChat,
+	"freshchat.com":   categorySupportChat,
+	"olark.com":       categorySupportChat,
+	"userlike.com":    categorySupportChat,
+
+	// content delivery networks
+	"cdn.jsdelivr.net":           categoryCDN,
+	"cdnjs.cloudflare.com":       categoryCDN,
+	"unpkg.com":                  categoryCDN,
+	"code.jquery.com":            categoryCDN,
+	"ajax.googleapis.com":        categoryCDN,
+	"stackpath.bootstrapcdn.com": categoryCDN,
+	"maxcdn.bootstrapcdn.com":    categoryCDN,
+	"cdn.cloudflare.com":         categoryCDN,
+	"cloudfront.net":             categoryCDN,
+	"akamaihd.net":               categoryCDN,
+	"fastly.net":                 categoryCDN,
+	"ajax.aspnetcdn.com":         categoryCDN,
+}
+
+// serviceCategory returns the category of the service a url belongs to, or
+// categoryOther for unknown hosts
+func serviceCategory(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return categoryOther
+	}
+	return hostCategory(parsed.Hostname())
+}
+
+// hostCategory returns the category of the service a host belongs to, or
+// categoryOther for unknown hosts
+func hostCategory(host string) string {
+	host = strings.ToLower(host)
+	for host != "" {
+		if category, ok := knownServices[host]; ok {
+			return category
+		}
+		_, parent, found := strings.Cut(host, ".")
+		if !found {
+			break
+		}
+		host = parent
+	}
+	return categoryOther
+}
+
+// groupByCategory splits the urls into the service categories, keeping their
+// order within each category
+func groupByCategory(urls []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, u := range urls {
+		category := serviceCategory(u)
+		groups[category] = append(groups[category], u)
+	}
+	return groups
+}
+
+// hostnameRegexp matches things looking like hostnames in code
+var hostnameRegexp = regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+[a-z]{2,}\b`)
+
+// findTrackerHosts returns the hosts of known services in a piece of code,
+// leaving out content delivery networks and unknown hosts
+func findTrackerHosts(code string) []string {
+	var hosts []string
+	for _, host := range hostnameRegexp.FindAllString(code, -1) {
+		host = strings.ToLower(host)
+		category := hostCategory(host)
+		if category == categoryOther || category == categoryCDN {
+			continue
+		}
+		if !slices.Contains(hosts, host) {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// embedServices are video and social media players which are embedded in
+// iframes, matched in order against the iframe url
+var embedServices = []struct {
+	name  string
+	match string
+}{
+	{"YouTube (privacy-enhanced mode)", "youtube-nocookie.com/"},
+	{"YouTube", "youtube.com/"},
+	{"Vimeo", "player.vimeo.com/"},
+	{"Twitter", "platform.twitter.com/"},
+	{"Facebook", "facebook.com/plugins/"},
+}
+
+// embedService returns the name of the video or social media service an
+// iframe url belongs to, or an empty string if it is none of them
+func embedService(u string) string {
+	for _, service := range embedServices {
+		if strings.Contains(u, service.match) {
+			return service.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/exp/slices"
+)
+
+// sitemap covers both a urlset and a sitemap index file
+type sitemap struct {
+	Urls []struct {
+		Loc string `xml:"loc"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// maxSitemaps bounds the number of sitemap files fetched via index files
+const maxSitemaps = 50
+
+// fetchSitemap returns all page urls listed in the sitemap at sitemapUrl,
+// following sitemap index files on the allowed hosts. prepare sets the
+// headers of each request.
+func fetchSitemap(ctx context.Context, client *http.Client, sitemapUrl string, allowedHosts []string, prepare func(*http.Request)) ([]string, error) {
+	var pages []string
+	queue := []string{sitemapUrl}
+	seen := map[string]bool{sitemapUrl: true}
+
+	for len(queue) > 0 && len(seen) <= maxSitemaps {
+		current := queue[0]
+		queue = queue[1:]
+
+		s, err := getSitemap(ctx, client, current, prepare)
+		if err != nil {
+			// only the root sitemap is required, broken index entries are skipped
+			if current == sitemapUrl {
+				return nil, err
+			}
+			continue
+		}
+		for _, u := range s.Urls {
+			pages = append(pages, u.Loc)
+		}
+		for _, child := range s.Sitemaps {
+			if u, err := url.Parse(child.Loc); err != nil || !slices.Contains(allowedHosts, u.Hostname()) {
+				slog.Debug("SKIPPED sitemap outside of the crawled hosts", "url", child.Loc)
+				continue
+			}
+			if !seen[child.Loc] {
+				seen[child.Loc] = true
+				queue = append(queue, child.Loc)
+			}
+		}
+	}
+	return pages, nil
+}
+
+func getSitemap(ctx context.Context, client *http.Client, sitemapUrl string, prepare func(*http.Request)) (*sitemap, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	prepare(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", sitemapUrl, resp.Status)
+	}
+
+	var s sitemap
+	if err := xml.NewDecoder(resp.Body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", sitemapUrl, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// TestSitemapOfProtectedSite checks the sitemap is requested with the
+// headers, credentials and cookies of the website and only from its hosts
+func TestSitemapOfProtectedSite(t *testing.T) {
+	var otherHits atomic.Int32
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		otherHits.Add(1)
+	}))
+	defer other.Close()
+	// the same server as another host, ports don't make a host of their own
+	otherUrl := strings.Replace(other.URL, "127.0.0.1", "localhost", 1)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		cookie, _ := r.Cookie("session")
+		if username != "user" || password != "pass" || r.Header.Get("X-Token") != "secret" || cookie == nil || cookie.Value != "abc" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<sitemapindex><sitemap><loc>%s/pages.xml</loc></sitemap><sitemap><loc>%s/sitemap.xml</loc></sitemap></sitemapindex>`, server.URL, otherUrl)
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/hidden.html</loc></url><url><loc>%s/elsewhere.html</loc></url></urlset>`, server.URL, otherUrl)
+		case "/hidden.html":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="stylesheet" href="https://fonts.googleapis.com/css?family=Hidden">`)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html></html>`)
+		}
+	}))
+	defer server.Close()
+
+	setOption(t, useSitemap, true)
+	setOption(t, basicAuth, "user:pass")
+	setOption(t, &headers, stringList{"X-Token: secret"})
+	setOption(t, &cookieValues, stringList{"session=abc"})
+	scanResult := scan(t, server.URL+"/")
+
+	if len(scanResult.googleFontsLinks) != 1 {
+		t.Errorf("Google Fonts links = %v, want the one of the page listed in the sitemap", scanResult.googleFontsLinks)
+	}
+	if hits := otherHits.Load(); hits != 0 {
+		t.Errorf("another host was requested %d times", hits)
+	}
+}
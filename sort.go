@@ -0,0 +1,109 @@
+package main
+
+import (
+	"golang.org/x/exp/slices"
+)
+
+// sortResults sorts the findings of a scan, which are collected in the
+// random order of the async crawl, so scans of the same website give the
+// same report. The redirect chain keeps its order.
+func (scanResult *ScanResult) sortResults() {
+	for _, list := range []*[]string{
+		&scanResult.googleAnalyticsScripts,
+		&scanResult.googleAnalyticsIFrames,
+		&scanResult.googleTagManagerScripts,
+		&scanResult.googleTagManagerIFrames,
+		&scanResult.googleFontsLinks,
+		&scanResult.googleFontsCss,
+		&scanResult.googleFontsStyle,
+		&scanResult.otherLinks,
+		&scanResult.otherScripts,
+		&scanResult.otherIFrames,
+		&scanResult.otherCss,
+		&scanResult.otherPreconnect,
+		&scanResult.otherPreload,
+		&scanResult.otherPrefetch,
+		&scanResult.otherStyle,
+		&scanResult.inlineStyleUrls,
+		&scanResult.otherImages,
+		&scanResult.media,
+		&scanResult.trackingPixels,
+		&scanResult.noscriptTrackers,
+		&scanResult.remoteFonts,
+		&scanResult.recaptcha,
+		&scanResult.hcaptcha,
+		&scanResult.consentScripts,
+		&scanResult.scriptEndpoints,
+		&scanResult.workers,
+		&scanResult.matomo,
+		&scanResult.ampScripts,
+		&scanResult.ampComponents,
+		&scanResult.ampAnalytics,
+		&scanResult.inlineReferences,
+		&scanResult.socialEmbeds,
+		&scanResult.otherRedirects,
+		&scanResult.externalLinks,
+		&scanResult.retried,
+		&scanResult.failed,
+		&scanResult.deadHosts,
+		&scanResult.pages,
+	} {
+		slices.Sort(*list)
+	}
+	for _, pages := range scanResult.foundOn {
+		slices.Sort(pages)
+	}
+
+	slices.SortFunc(scanResult.trackers, func(a, b trackerFinding) bool {
+		return a.Tracker+" "+a.Id < b.Tracker+" "+b.Id
+	})
+	for _, tracker := range scanResult.trackers {
+		slices.Sort(tracker.Methods)
+	}
+	slices.SortFunc(scanResult.matchedRules, func(a, b ruleMatch) bool {
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		if a.AppliesTo != b.AppliesTo {
+			return a.AppliesTo < b.AppliesTo
+		}
+		return a.Resource < b.Resource
+	})
+	for _, match := range scanResult.matchedRules {
+		slices.Sort(match.Pages)
+	}
+	slices.SortFunc(scanResult.formEndpoints, func(a, b formEndpoint) bool {
+		return a.Url+" "+a.Method < b.Url+" "+b.Method
+	})
+	for _, form := range scanResult.formEndpoints {
+		slices.Sort(form.Pages)
+	}
+	slices.SortFunc(scanResult.mixedContent, func(a, b mixedContent) bool {
+		return a.Url+" "+a.Page < b.Url+" "+b.Page
+	})
+	slices.SortFunc(scanResult.clientRedirects, func(a, b clientRedirect) bool {
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		return a.Url+" "+a.Via < b.Url+" "+b.Via
+	})
+	slices.SortFunc(scanResult.brokenPages, func(a, b brokenPage) bool { return a.Url < b.Url })
+	slices.SortFunc(scanResult.redirects, func(a, b redirect) bool {
+		return a.From+" "+a.To < b.From+" "+b.To
+	})
+	slices.SortFunc(scanResult.externalChecks, func(a, b externalCheck) bool { return a.Url < b.Url })
+	slices.SortFunc(scanResult.cspOrigins, func(a, b cspOrigin) bool {
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		return !a.ReportOnly && b.ReportOnly
+	})
+	slices.SortFunc(scanResult.sriChecks, func(a, b sriCheck) bool { return a.Url < b.Url })
+	slices.SortFunc(scanResult.tlsIssues, func(a, b tlsIssue) bool { return a.Host < b.Host })
+	slices.SortFunc(scanResult.cookies, func(a, b cookieInfo) bool {
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+		return a.Name < b.Name
+	})
+}
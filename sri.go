@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log/slog"
+
+	"golang.org/x/exp/slices"
+)
+
+// sriCheck is whether a 3rd party script or stylesheet is loaded with
+// Subresource Integrity, so a compromised host can't change what runs on
+// the website
+type sriCheck struct {
+	Url         string `json:"url"`
+	Element     string `json:"element"`
+	Integrity   bool   `json:"integrity"`
+	CrossOrigin string `json:"crossOrigin"`
+}
+
+// addSriCheck records the integrity and crossorigin attributes of a 3rd
+// party script or stylesheet. A resource counts as protected if it has an
+// integrity hash on any page. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addSriCheck(u, element, integrity, crossOrigin string) {
+	i := slices.IndexFunc(scanResult.sriChecks, func(check sriCheck) bool { return check.Url == u })
+	if i < 0 {
+		scanResult.sriChecks = append(scanResult.sriChecks, sriCheck{Url: u, Element: element})
+		i = len(scanResult.sriChecks) - 1
+		if integrity == "" {
+			slog.Info("NO SRI", "element", element, "url", u)
+		}
+	}
+	if integrity != "" {
+		scanResult.sriChecks[i].Integrity = true
+		scanResult.sriChecks[i].CrossOrigin = crossOrigin
+	}
+}
+
+// withoutIntegrity returns the 3rd party scripts and stylesheets loaded
+// without Subresource Integrity
+func (scanResult *ScanResult) withoutIntegrity() []string {
+	var urls []string
+	for _, check := range scanResult.sriChecks {
+		if !check.Integrity {
+			urls = append(urls, check.Url)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/net/publicsuffix"
+)
+
+// ScanStats are aggregate numbers of the 3rd party footprint of a website
+type ScanStats struct {
+	ThirdPartyHosts      int `json:"thirdPartyHosts"`
+	GoogleAnalyticsPages int `json:"googleAnalyticsPages"`
+	ExternalRequests     int `json:"externalRequests"`
+	FontProviders        int `json:"fontProviders"`
+}
+
+// Stats counts the distinct 3rd party hosts and font providers, the pages
+// loading Google Analytics and the references to 3rd party resources across
+// all pages. Inline references are left out as they are no requests.
+func (scanResult *ScanResult) Stats() ScanStats {
+	var stats ScanStats
+	var hosts, seen []string
+	for _, list := range scanResult.resourceLists() {
+		if list.resourceType == "inline-reference" {
+			continue
+		}
+		for _, u := range list.urls {
+			if slices.Contains(seen, u) {
+				continue
+			}
+			seen = append(seen, u)
+			stats.ExternalRequests += len(scanResult.foundOn[u])
+			if host := resourceHost(u); !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	stats.ThirdPartyHosts = len(hosts)
+
+	var gaPages []string
+	for _, u := range append(slices.Clone(scanResult.googleAnalyticsScripts), scanResult.googleAnalyticsIFrames...) {
+		for _, page := range scanResult.foundOn[u] {
+			if !slices.Contains(gaPages, page) {
+				gaPages = append(gaPages, page)
+			}
+		}
+	}
+	stats.GoogleAnalyticsPages = len(gaPages)
+
+	var providers []string
+	for _, list := range [][]string{scanResult.googleFontsLinks, scanResult.googleFontsCss, scanResult.googleFontsStyle, scanResult.remoteFonts} {
+		for _, u := range list {
+			if provider := fontProvider(u); !slices.Contains(providers, provider) {
+				providers = append(providers, provider)
+			}
+		}
+	}
+	stats.FontProviders = len(providers)
+	return stats
+}
+
+// resourceHost returns the host of a resource url, resources found as bare
+// hosts are returned as they are
+func resourceHost(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil || parsed.Host == "" {
+		return strings.ToLower(u)
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// fontProvider names the provider serving a font url by its registrable
+// domain, the hosts of Google Fonts count as one provider
+func fontProvider(u string) string {
+	if isGoogleFontsUrl(u) {
+		return "Google Fonts"
+	}
+	host := resourceHost(u)
+	if root, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return root
+	}
+	return host
+}
+
+// printStats writes the statistics of the scan on one line
+func printStats(w io.Writer, stats ScanStats) {
+	fmt.Fprintf(w, "Statistics: %d 3rd party hosts, %d external requests, %d pages with Google Analytics, %d font providers\n",
+		stats.ThirdPartyHosts, stats.ExternalRequests, stats.GoogleAnalyticsPages, stats.FontProviders)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// brokenPage is a page of the website answering with a 4xx or 5xx status
+type brokenPage struct {
+	Url    string `json:"url"`
+	Status int    `json:"status"`
+}
+
+// addStatus counts the status code of a response, pages of the website
+// answering with an error are remembered as broken.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addStatus(status int, u *url.URL, page bool, domain string) {
+	if status == 0 {
+		return
+	}
+	if scanResult.statusCounts == nil {
+		scanResult.statusCounts = map[int]int{}
+	}
+	scanResult.statusCounts[status] += 1
+	if status >= 400 && page && isSameDomain(u.String(), domain) {
+		scanResult.brokenPages = append(scanResult.brokenPages, brokenPage{Url: u.String(), Status: status})
+	}
+}
+
+// printStatusCounts writes the number of responses per status code, grouped
+// by class like 2xx
+func printStatusCounts(w io.Writer, counts map[int]int) {
+	var statuses []int
+	classes := map[int]int{}
+	for status, count := range counts {
+		statuses = append(statuses, status)
+		classes[status/100] += count
+	}
+	slices.Sort(statuses)
+	var parts []string
+	for class := 1; class <= 5; class++ {
+		if classes[class] == 0 {
+			continue
+		}
+		var details []string
+		for _, status := range statuses {
+			if status/100 == class {
+				details = append(details, fmt.Sprintf("%d: %d", status, counts[status]))
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%dxx %d (%s)", class, classes[class], strings.Join(details, ", ")))
+	}
+	fmt.Fprintln(w, "Response status codes:", strings.Join(parts, ", "))
+}
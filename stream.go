@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// streamFinding is one line of the -stream output. Findings which are no
+// plain urls have their entry of the -json list as detail.
+type streamFinding struct {
+	Site   string    `json:"site"`
+	Type   string    `json:"type"`
+	Url    string    `json:"url"`
+	Page   string    `json:"page"`
+	Detail any       `json:"detail,omitempty"`
+	Agent  string    `json:"agent,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+var (
+	streamMu sync.Mutex
+	// streamEncoder writes to stdout or the file of -o
+	streamEncoder = json.NewEncoder(os.Stdout)
+)
+
+// emitFinding writes the finding as a json line to the output right away
+func emitFinding(finding streamFinding) {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	if err := streamEncoder.Encode(finding); err != nil {
+		log.Fatal("error writing finding: ", err)
+	}
+}
+
+// emit writes a finding of the type as json line with -stream.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) emit(findingType, resource, page string, detail any) {
+	if !*stream {
+		return
+	}
+	emitFinding(streamFinding{
+		Site:   scanResult.url,
+		Type:   findingType,
+		Url:    resource,
+		Page:   page,
+		Detail: detail,
+		Agent:  scanResult.agent(page),
+		Time:   time.Now(),
+	})
+}
+
+// listName returns the name of one of the resource lists of the scan result,
+// as used for the type of streamed findings
+func (scanResult *ScanResult) listName(list *[]string) string {
+	switch list {
+	case &scanResult.googleAnalyticsScripts:
+		return "googleAnalyticsScripts"
+	case &scanResult.googleAnalyticsIFrames:
+		return "googleAnalyticsIFrames"
+	case &scanResult.googleTagManagerScripts:
+		return "googleTagManagerScripts"
+	case &scanResult.googleTagManagerIFrames:
+		return "googleTagManagerIFrames"
+	case &scanResult.googleFontsLinks:
+		return "googleFontsLinks"
+	case &scanResult.googleFontsCss:
+		return "googleFontsCss"
+	case &scanResult.googleFontsStyle:
+		return "googleFontsStyle"
+	case &scanResult.otherLinks:
+		return "otherLinks"
+	case &scanResult.otherScripts:
+		return "otherScripts"
+	case &scanResult.otherIFrames:
+		return "otherIFrames"
+	case &scanResult.otherCss:
+		return "otherCss"
+	case &scanResult.otherPreconnect:
+		return "otherPreconnect"
+	case &scanResult.otherPreload:
+		return "otherPreload"
+	case &scanResult.otherPrefetch:
+		return "otherPrefetch"
+	case &scanResult.otherStyle:
+		return "otherStyle"
+	case &scanResult.inlineStyleUrls:
+		return "inlineStyleUrls"
+	case &scanResult.otherImages:
+		return "otherImages"
+	case &scanResult.media:
+		return "media"
+	case &scanResult.trackingPixels:
+		return "trackingPixels"
+	case &scanResult.noscriptTrackers:
+		return "noscriptTrackers"
+	case &scanResult.remoteFonts:
+		return "remoteFonts"
+	case &scanResult.recaptcha:
+		return "recaptcha"
+	case &scanResult.hcaptcha:
+		return "hcaptcha"
+	case &scanResult.consentScripts:
+		return "consentScripts"
+	case &scanResult.matomo:
+		return "matomo"
+	case &scanResult.ampScripts:
+		return "ampScripts"
+	case &scanResult.inlineReferences:
+		return "inlineReferences"
+	case &scanResult.scriptEndpoints:
+		return "scriptEndpoints"
+	case &scanResult.workers:
+		return "workers"
+	case &scanResult.socialEmbeds:
+		return "socialEmbeds"
+	case &scanResult.otherRedirects:
+		return "otherRedirects"
+	}
+	return "unknown"
+}
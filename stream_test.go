@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// TestStreamFindings checks findings which are no plain urls are streamed
+// as well
+func TestStreamFindings(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<script src="https://www.googletagmanager.com/gtag/js?id=G-ABC123"></script>
+<form action="https://forms.other.com/submit" method="post"></form>
+<meta http-equiv="refresh" content="5; url=https://affiliate.other.com/">`)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	setOption(t, stream, true)
+	setOption(t, &streamEncoder, json.NewEncoder(&out))
+	scan(t, server.URL+"/")
+
+	var types []string
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var finding streamFinding
+		if err := decoder.Decode(&finding); err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, finding.Type)
+	}
+	for _, want := range []string{"googleAnalyticsScripts", "trackers", "formEndpoints", "cookies", "clientRedirects"} {
+		if !slices.Contains(types, want) {
+			t.Errorf("streamed %v, missing %s", types, want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// tableRow is a line of the -table output, all 3rd party resources of a type
+type tableRow struct {
+	resourceType string
+	count        int
+	sampleHost   string
+	google       bool
+}
+
+// tableRows groups the 3rd party resources by type, in the order of
+// resourceLists
+func (scanResult *ScanResult) tableRows() []*tableRow {
+	var rows []*tableRow
+	byType := map[string]*tableRow{}
+	for _, list := range scanResult.resourceLists() {
+		if len(list.urls) == 0 {
+			continue
+		}
+		row, ok := byType[list.resourceType]
+		if !ok {
+			row = &tableRow{resourceType: list.resourceType, sampleHost: strings.TrimPrefix(resourceHost(list.urls[0]), "www.")}
+			byType[list.resourceType] = row
+			rows = append(rows, row)
+		}
+		row.count += len(list.urls)
+		row.google = row.google || list.googleAnalytics || list.googleFonts || list.tagManager
+	}
+	return rows
+}
+
+// printTable writes the 3rd party resources as aligned columns of type, count
+// and a sample host, with the hosts of Google services in red
+func printTable(w io.Writer, scanResult *ScanResult, useColor bool) {
+	color := colorizer(useColor).color
+	rows := scanResult.tableRows()
+	if len(rows) == 0 {
+		fmt.Fprintln(w, "No 3rd party resources found")
+		return
+	}
+	// only the last column is colored, escape sequences in the aligned
+	// columns would count as width
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tCOUNT\tSAMPLE HOST")
+	for _, row := range rows {
+		code := colorYellow
+		if row.google {
+			code = colorRed
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s%s%s\n", row.resourceType, row.count, color(code), row.sampleHost, color(colorReset))
+	}
+	tw.Flush()
+}
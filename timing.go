@@ -0,0 +1,157 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// requestTiming is how long the phases of a single request took. DNS and
+// connect are zero if a kept-alive connection was reused.
+type requestTiming struct {
+	Host    string
+	Type    string
+	DNS     time.Duration
+	Connect time.Duration
+	TTFB    time.Duration
+	Total   time.Duration
+}
+
+// timingStats aggregates the timings of all requests of a host or type,
+// durations are encoded as nanoseconds
+type timingStats struct {
+	Key        string        `json:"key"`
+	Count      int           `json:"count"`
+	Min        time.Duration `json:"minNs"`
+	Avg        time.Duration `json:"avgNs"`
+	Max        time.Duration `json:"maxNs"`
+	AvgTTFB    time.Duration `json:"avgTtfbNs"`
+	AvgDNS     time.Duration `json:"avgDnsNs"`
+	AvgConnect time.Duration `json:"avgConnectNs"`
+}
+
+// timingTransport measures each request with httptrace and reports the
+// timing once the response body is closed
+type timingTransport struct {
+	base     http.RoundTripper
+	onTiming func(requestTiming)
+}
+
+func (t *timingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var timing requestTiming
+	var dnsStart, connectStart time.Time
+	start := time.Now()
+	trace := &httptrace.ClientTrace{
+		DNSStart:             func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone:              func(httptrace.DNSDoneInfo) { timing.DNS = time.Since(dnsStart) },
+		ConnectStart:         func(string, string) { connectStart = time.Now() },
+		ConnectDone:          func(string, string, error) { timing.Connect = time.Since(connectStart) },
+		TLSHandshakeDone:     func(tls.ConnectionState, error) { timing.Connect = time.Since(connectStart) },
+		GotFirstResponseByte: func() { timing.TTFB = time.Since(start) },
+	}
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		return resp, err
+	}
+	timing.Host = req.URL.Host
+	timing.Type = contentKind(resp.Header.Get("Content-Type"))
+	resp.Body = &timedBody{ReadCloser: resp.Body, done: func() {
+		timing.Total = time.Since(start)
+		t.onTiming(timing)
+	}}
+	return resp, nil
+}
+
+// timedBody calls done when the body is closed for the first time
+type timedBody struct {
+	io.ReadCloser
+	once sync.Once
+	done func()
+}
+
+func (b *timedBody) Close() error {
+	b.once.Do(b.done)
+	return b.ReadCloser.Close()
+}
+
+// contentKind sorts a content type into html, css or other
+func contentKind(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "html"):
+		return "html"
+	case strings.Contains(contentType, "css"):
+		return "css"
+	}
+	return "other"
+}
+
+// aggregateTimings groups the timings by the given key, sorted by key
+func aggregateTimings(timings []requestTiming, key func(requestTiming) string) []timingStats {
+	groups := map[string][]requestTiming{}
+	for _, timing := range timings {
+		groups[key(timing)] = append(groups[key(timing)], timing)
+	}
+	var stats []timingStats
+	for k, group := range groups {
+		s := timingStats{Key: k, Count: len(group), Min: group[0].Total}
+		var total, ttfb, dns, connect time.Duration
+		var dnsCount, connectCount int
+		for _, timing := range group {
+			if timing.Total < s.Min {
+				s.Min = timing.Total
+			}
+			if timing.Total > s.Max {
+				s.Max = timing.Total
+			}
+			total += timing.Total
+			ttfb += timing.TTFB
+			if timing.DNS > 0 {
+				dns += timing.DNS
+				dnsCount++
+			}
+			if timing.Connect > 0 {
+				connect += timing.Connect
+				connectCount++
+			}
+		}
+		s.Avg = total / time.Duration(len(group))
+		s.AvgTTFB = ttfb / time.Duration(len(group))
+		if dnsCount > 0 {
+			s.AvgDNS = dns / time.Duration(dnsCount)
+		}
+		if connectCount > 0 {
+			s.AvgConnect = connect / time.Duration(connectCount)
+		}
+		stats = append(stats, s)
+	}
+	slices.SortFunc(stats, func(a, b timingStats) bool { return a.Key < b.Key })
+	return stats
+}
+
+// printTimings writes one line of timing statistics per group
+func printTimings(w io.Writer, title string, stats []timingStats) {
+	fmt.Fprintln(w, title)
+	for _, s := range stats {
+		fmt.Fprintf(w, "  %s: %d responses, min %s, avg %s, max %s, avg TTFB %s",
+			s.Key, s.Count, formatMs(s.Min), formatMs(s.Avg), formatMs(s.Max), formatMs(s.AvgTTFB))
+		if s.AvgDNS > 0 {
+			fmt.Fprintf(w, ", avg DNS %s", formatMs(s.AvgDNS))
+		}
+		if s.AvgConnect > 0 {
+			fmt.Fprintf(w, ", avg connect %s", formatMs(s.AvgConnect))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// formatMs formats a duration as milliseconds with one decimal, like 12.3ms
+func formatMs(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
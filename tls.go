@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// tlsInfo is the TLS connection to the host of the website
+type tlsInfo struct {
+	Host     string    `json:"host"`
+	Version  string    `json:"version"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notAfter"`
+}
+
+// tlsIssue is a certificate of a host which failed verification
+type tlsIssue struct {
+	Host  string `json:"host"`
+	Error string `json:"error"`
+}
+
+// tlsTransport passes the TLS connection state of each https response to
+// onTLS
+type tlsTransport struct {
+	base  http.RoundTripper
+	onTLS func(host string, state *tls.ConnectionState)
+}
+
+func (t *tlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && resp.TLS != nil {
+		t.onTLS(req.URL.Hostname(), resp.TLS)
+	}
+	return resp, err
+}
+
+// newTLSInfo describes the TLS connection to a host by its version and the
+// issuer and expiry of the certificate
+func newTLSInfo(host string, state *tls.ConnectionState) tlsInfo {
+	info := tlsInfo{Host: host, Version: tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Issuer = cert.Issuer.String()
+		info.NotAfter = cert.NotAfter
+	}
+	return info
+}
+
+// certificateIssue returns why a request failed the verification of the
+// certificate, like expired, for another host or self-signed, or ""
+func certificateIssue(err error) string {
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &invalid) || errors.As(err, &hostname) || errors.As(err, &unknownAuthority) {
+		var verification *tls.CertificateVerificationError
+		if errors.As(err, &verification) {
+			return verification.Err.Error()
+		}
+		return err.Error()
+	}
+	return ""
+}
+
+// addTLSIssue records a certificate problem of a host once.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addTLSIssue(host, issue string) {
+	if slices.IndexFunc(scanResult.tlsIssues, func(known tlsIssue) bool { return known.Host == host }) >= 0 {
+		return
+	}
+	scanResult.tlsIssues = append(scanResult.tlsIssues, tlsIssue{Host: host, Error: issue})
+}
+
+// printTLS writes the TLS version and certificate of the website and the
+// certificate problems found
+func printTLS(w io.Writer, info tlsInfo, issues []tlsIssue, color func(string) string) {
+	if info.Host != "" {
+		fmt.Fprintf(w, "TLS of %s: %s, certificate issued by %s, valid until %s\n",
+			info.Host, info.Version, info.Issuer, info.NotAfter.Format(time.DateOnly))
+	}
+	if len(issues) > 0 {
+		fmt.Fprintln(w, color(colorRed)+"TLS certificate problems:"+color(colorReset))
+		for _, issue := range issues {
+			fmt.Fprintf(w, "  %s: %s\n", issue.Host, issue.Error)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation scope of the spans of the scans
+const tracerName = "github.com/chaosbiber/threepwoods-colly"
+
+// setupTracing exports the spans of all scans to the OTLP/HTTP endpoint, like
+// http://localhost:4318, in batches while the scans run. shutdown sends the
+// spans which are left and has to be called before exiting.
+func setupTracing(endpoint string) (shutdown func(context.Context) error, err error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid endpoint %q, use an url like http://localhost:4318", endpoint)
+	}
+	// like OTEL_EXPORTER_OTLP_ENDPOINT the endpoint is the base url the
+	// path of traces is appended to
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(u.Host),
+		otlptracehttp.WithURLPath(path.Join("/", u.Path, "v1/traces")),
+	}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(context.Background(),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithSchemaURL(semconv.SchemaURL),
+		resource.WithAttributes(semconv.ServiceName("threepwoods-colly"), semconv.ServiceVersion(version)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// startScanSpan starts the root span of a scan of the website, the spans of
+// its requests are children of the span in the returned context
+func startScanSpan(ctx context.Context, website string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "scan", trace.WithAttributes(semconv.URLFull(website)))
+}
+
+// traceTransport records a span for each request which is actually sent,
+// including requests of robots.txt and each hop of a redirect. It ends once
+// the response body is closed, the parent is the span in the context of the
+// request.
+type traceTransport struct {
+	base http.RoundTripper
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := otel.Tracer(tracerName).Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(req.Method), semconv.URLFull(req.URL.String())))
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return resp, err
+	}
+	span.SetAttributes(
+		semconv.HTTPResponseStatusCode(resp.StatusCode),
+		attribute.String("resource.type", contentKind(resp.Header.Get("Content-Type"))),
+	)
+	if resp.StatusCode >= 400 {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	resp.Body = &tracedBody{ReadCloser: resp.Body, span: span}
+	return resp, nil
+}
+
+// tracedBody counts the bytes read of a response body and ends the span of
+// the request when the body is closed for the first time
+type tracedBody struct {
+	io.ReadCloser
+	span  trace.Span
+	size  int
+	ended bool
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	if !b.ended {
+		b.ended = true
+		b.span.SetAttributes(semconv.HTTPResponseBodySize(b.size))
+		b.span.End()
+	}
+	return b.ReadCloser.Close()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+	collectortrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"golang.org/x/exp/slices"
+	"google.golang.org/protobuf/proto"
+)
+
+// TestTraceOfScan checks the spans of the requests sent are exported below
+// the root span of the scan, and requests skipped by a filter get none
+func TestTraceOfScan(t *testing.T) {
+	var mu sync.Mutex
+	var spans []*tracepb.Span
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/otlp/v1/traces" {
+			t.Errorf("spans sent to %s, want /otlp/v1/traces", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		var request collectortrace.ExportTraceServiceRequest
+		if err := proto.Unmarshal(body, &request); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+	}))
+	defer collector.Close()
+
+	server := serveFiles(t, map[string]string{
+		"/index.html":       `<link rel="stylesheet" href="/style.css"><a href="/about.html">about</a><a href="/admin/users.html">users</a>`,
+		"/style.css":        `body { color: black; }`,
+		"/about.html":       `<html></html>`,
+		"/admin/users.html": `<html></html>`,
+	})
+
+	setOption(t, otelEndpoint, collector.URL+"/otlp")
+	setOption(t, &excludePath, regexp.MustCompile(`^/admin/`))
+	shutdown, err := setupTracing(*otelEndpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	scan(t, server.URL+"/")
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	var root *tracepb.Span
+	for _, span := range spans {
+		if span.Name == "scan" {
+			root = span
+		}
+	}
+	if root == nil {
+		t.Fatalf("no root span in %d spans", len(spans))
+	}
+	var urls []string
+	for _, span := range spans {
+		if span == root {
+			continue
+		}
+		if !bytes.Equal(span.TraceId, root.TraceId) || !bytes.Equal(span.ParentSpanId, root.SpanId) {
+			t.Errorf("span %s is no child of the root span", span.Name)
+		}
+		for _, attribute := range span.Attributes {
+			if attribute.Key == "url.full" {
+				urls = append(urls, attribute.Value.GetStringValue())
+			}
+		}
+	}
+	slices.Sort(urls)
+	want := []string{server.URL + "/", server.URL + "/about.html", server.URL + "/robots.txt", server.URL + "/style.css"}
+	if fmt.Sprint(urls) != fmt.Sprint(want) {
+		t.Errorf("spans of %v, want %v", urls, want)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// trackerIdRegexp matches Google Analytics measurement ids (G-, and UA- of
+// Universal Analytics) and Google Tag Manager container ids
+var trackerIdRegexp = regexp.MustCompile(`\b(G-[A-Z0-9]{6,12}|UA-\d{4,10}-\d{1,4}|GTM-[A-Z0-9]{4,8})\b`)
+
+// trackerFinding is a single tracker identified by its id, with the ways it
+// was detected: src, inline or iframe
+type trackerFinding struct {
+	Tracker string   `json:"tracker"`
+	Id      string   `json:"id"`
+	Methods []string `json:"methods"`
+}
+
+// trackerName returns the name of the tracker an id belongs to
+func trackerName(id string) string {
+	if strings.HasPrefix(id, "GTM-") {
+		return "Google Tag Manager"
+	}
+	return "Google Analytics"
+}
+
+// addTrackers records the tracker ids found in a url or code. If there are
+// none, a tracker without id is recorded unless fallback is empty.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addTrackers(code, method, fallback, page string) {
+	ids := trackerIdRegexp.FindAllString(code, -1)
+	if len(ids) == 0 && fallback != "" {
+		scanResult.addTracker(fallback, "", method, page)
+	}
+	for _, id := range ids {
+		scanResult.addTracker(trackerName(id), id, method, page)
+	}
+}
+
+// addTracker merges the detection method into the finding of the tracker.
+// The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addTracker(tracker, id, method, page string) {
+	i := slices.IndexFunc(scanResult.trackers, func(t trackerFinding) bool {
+		return t.Tracker == tracker && t.Id == id
+	})
+	if i < 0 {
+		scanResult.trackers = append(scanResult.trackers, trackerFinding{Tracker: tracker, Id: id})
+		i = len(scanResult.trackers) - 1
+	}
+	if !slices.Contains(scanResult.trackers[i].Methods, method) {
+		scanResult.trackers[i].Methods = append(scanResult.trackers[i].Methods, method)
+		scanResult.emit("trackers", id, page, trackerFinding{Tracker: tracker, Id: id, Methods: []string{method}})
+	}
+}
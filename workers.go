@@ -0,0 +1,24 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// workerRegexp matches absolute url literals of service workers registered
+// with navigator.serviceWorker.register() and of web workers started with
+// new Worker() or new SharedWorker() in code
+var workerRegexp = regexp.MustCompile(`\b(serviceWorker\.register|new\s+(?:Shared)?Worker)\(\s*["'` + "`" + `]((?:https?:)?//[^"'` + "`" + `\s]+)`)
+
+// addWorkers records the 3rd party service workers and web workers started
+// by code found at where. The caller must hold scanResult.mu.
+func (scanResult *ScanResult) addWorkers(code, where, domain string, base *url.URL, page string) {
+	for _, m := range workerRegexp.FindAllStringSubmatch(code, -1) {
+		worker := resolveUrl(base, m[2])
+		if isSameDomain(worker, domain) {
+			continue
+		}
+		scanResult.add(&scanResult.workers, worker, page)
+		scanResult.logger(page).Info("3RD PARTY worker, heuristic, unknown if that code executed", "call", m[1], "in", where, "page", page, "url", worker)
+	}
+}